@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fredrikln/the-ray-tracer-challenge-go/pkg/imagediff"
+)
+
+// runCompare implements the compare subcommand, which prints the metrics of
+// a test image against a reference and can write a false color diff image
+func runCompare(args []string) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	diff := flags.String("diff", "", "write a false color FLIP error image to this PNG file")
+	ppd := flags.Float64("ppd", imagediff.DefaultPixelsPerDegree, "pixels per degree of visual angle for FLIP")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: compare [flags] reference test")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	res, err := imagediff.CompareFiles(flags.Arg(0), flags.Arg(1), *ppd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(res)
+
+	if *diff != "" {
+		if err := res.DiffImage().SavePNG(*diff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return 0
+}
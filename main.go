@@ -18,8 +18,7 @@ func GetTestScene1() (*r.World, *r.Matrix) {
 
 	g := r.NewGroup()
 
-	c := r.NewColor(0, 0, 0)
-	w.Background = &c
+	w.Background = r.NewConstantBackground(r.NewColor(0, 0, 0))
 
 	// -- Plane as floor ---
 	// floor := r.NewPlane()
@@ -143,6 +142,10 @@ func startProfiling() func() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		os.Exit(runCompare(os.Args[2:]))
+	}
+
 	stop := startProfiling()
 	defer stop()
 
@@ -157,7 +160,6 @@ func main() {
 	camera := r.NewCamera(width, int(float64(width)/ratio), math.Pi/3).SetTransform(ct)
 	camera.Samples = 10
 	camera.Depth = 10
-	camera.GammaCorrection = true
 
 	timeBefore := time.Now()
 
@@ -169,7 +171,10 @@ func main() {
 
 	fmt.Println("Render time:", diff)
 	filename := fmt.Sprintf("render-%d-%d-%d-%s.png", time.Now().UnixMilli(), camera.Samples, camera.Depth, diff)
-	fmt.Println("Saved:", filename)
 
-	canvas.SavePNG(filename)
+	if err := canvas.SavePNG(filename); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Saved:", filename)
 }
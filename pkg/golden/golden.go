@@ -0,0 +1,176 @@
+package golden
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fredrikln/the-ray-tracer-challenge-go/pkg/imagediff"
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// DefaultTolerance is the mean FLIP error allowed for scenes that do not set
+// their own. Renders that only differ by rounding stay far below it.
+const DefaultTolerance = 0.02
+
+// Harness renders scenes and compares them to the reference images in Dir,
+// named <scene>.png. Update writes new references instead of comparing.
+// When FailureDir is set the render and a false color diff image of every
+// failing scene are written there for inspection.
+type Harness struct {
+	Dir        string
+	FailureDir string
+	Update     bool
+}
+
+func NewHarness(dir string) *Harness {
+	return &Harness{Dir: dir}
+}
+
+func (h *Harness) SetFailureDir(dir string) *Harness {
+	h.FailureDir = dir
+
+	return h
+}
+
+func (h *Harness) SetUpdate(update bool) *Harness {
+	h.Update = update
+
+	return h
+}
+
+// Result is the outcome of checking one scene
+type Result struct {
+	Scene     string
+	Tolerance float64
+	Metrics   *imagediff.Result
+	Updated   bool
+	// Output and Diff are where the failing render and its diff image were
+	// written, if anywhere
+	Output string
+	Diff   string
+}
+
+func (res *Result) Passed() bool {
+	return res.Updated || res.Metrics.FLIP <= res.Tolerance
+}
+
+func (res *Result) String() string {
+	if res.Updated {
+		return fmt.Sprintf("%s: reference updated", res.Scene)
+	}
+
+	status := "ok"
+	if !res.Passed() {
+		status = "FAIL"
+	}
+
+	s := fmt.Sprintf("%s: %s FLIP %.4f (tolerance %.4f) SSIM %.4f PSNR %.2f dB",
+		res.Scene, status, res.Metrics.FLIP, res.Tolerance, res.Metrics.SSIM, res.Metrics.PSNR)
+
+	if res.Output != "" {
+		s += fmt.Sprintf("\n  render: %s\n  diff:   %s", res.Output, res.Diff)
+	}
+
+	return s
+}
+
+func (h *Harness) referencePath(s Scene) string {
+	return filepath.Join(h.Dir, s.Name+".png")
+}
+
+// Check renders a scene and compares it to its reference, or writes the
+// reference when updating
+func (h *Harness) Check(s Scene) (*Result, error) {
+	w, camera := s.Build()
+	canvas := camera.Render(w)
+
+	res := &Result{Scene: s.Name, Tolerance: s.Tolerance}
+	if res.Tolerance == 0 {
+		res.Tolerance = DefaultTolerance
+	}
+
+	if h.Update {
+		if err := os.MkdirAll(h.Dir, 0755); err != nil {
+			return nil, err
+		}
+
+		if err := canvas.SavePNG(h.referencePath(s)); err != nil {
+			return nil, err
+		}
+
+		res.Updated = true
+
+		return res, nil
+	}
+
+	reference, err := r.LoadCanvas(h.referencePath(s))
+	if err != nil {
+		return nil, fmt.Errorf("loading reference for %s: %w", s.Name, err)
+	}
+
+	res.Metrics, err = imagediff.Compare(reference, canvas, imagediff.DefaultPixelsPerDegree)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s: %w", s.Name, err)
+	}
+
+	if !res.Passed() && h.FailureDir != "" {
+		if err := h.saveFailure(res, canvas); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (h *Harness) saveFailure(res *Result, canvas *r.Canvas) error {
+	if err := os.MkdirAll(h.FailureDir, 0755); err != nil {
+		return err
+	}
+
+	res.Output = filepath.Join(h.FailureDir, res.Scene+".png")
+	res.Diff = filepath.Join(h.FailureDir, res.Scene+"-diff.png")
+
+	if err := canvas.SavePNG(res.Output); err != nil {
+		return err
+	}
+
+	return res.Metrics.DiffImage().SavePNG(res.Diff)
+}
+
+// Run checks every scene, stopping at the first one that cannot be rendered
+// or compared at all
+func (h *Harness) Run(scenes []Scene) ([]*Result, error) {
+	results := make([]*Result, 0, len(scenes))
+
+	for _, s := range scenes {
+		res, err := h.Check(s)
+		if err != nil {
+			return results, err
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// Report lists every result, failures first
+func Report(results []*Result) string {
+	var failed, passed []string
+
+	for _, res := range results {
+		if res.Passed() {
+			passed = append(passed, res.String())
+		} else {
+			failed = append(failed, res.String())
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%d of %d scenes failed", len(failed), len(results))}
+	lines = append(lines, failed...)
+	lines = append(lines, passed...)
+
+	return strings.Join(lines, "\n")
+}
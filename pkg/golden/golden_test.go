@@ -0,0 +1,103 @@
+package golden
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// Regenerate the references after an intended change with
+//
+//	go test ./pkg/golden -update
+var update = flag.Bool("update", false, "write new reference images instead of comparing")
+
+func TestGoldenImages(t *testing.T) {
+	h := NewHarness("testdata").SetFailureDir(filepath.Join("testdata", "failures")).SetUpdate(*update)
+
+	var results []*Result
+
+	for _, s := range Scenes {
+		s := s
+
+		t.Run(s.Name, func(t *testing.T) {
+			res, err := h.Check(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results = append(results, res)
+
+			if !res.Passed() {
+				t.Errorf("%v", res)
+			}
+		})
+	}
+
+	t.Log("\n" + Report(results))
+}
+
+func TestScenesAreRepeatable(t *testing.T) {
+	for _, s := range Scenes {
+		w1, c1 := s.Build()
+		w2, c2 := s.Build()
+
+		a, b := c1.Render(w1), c2.Render(w2)
+
+		for i := range a.Pixels {
+			if a.Pixels[i] != b.Pixels[i] {
+				t.Fatalf("Got %v and %v at %d, want identical renders", a.Pixels[i], b.Pixels[i], i)
+			}
+		}
+	}
+}
+
+func TestHarnessReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+
+	white := Scene{Name: "scene", Build: func() (*r.World, *r.Camera) {
+		w := newWorld()
+		w.SetBackground(r.NewConstantBackground(r.NewColor(1, 1, 1)), false)
+
+		return w, newCamera(r.NewPoint(0, 0, -5), r.NewPoint(0, 0, 0))
+	}}
+
+	black := white
+	black.Build = func() (*r.World, *r.Camera) {
+		return newWorld(), newCamera(r.NewPoint(0, 0, -5), r.NewPoint(0, 0, 0))
+	}
+
+	if _, err := NewHarness(dir).SetUpdate(true).Check(white); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHarness(dir).SetFailureDir(filepath.Join(dir, "failures"))
+
+	testCases := []struct {
+		desc  string
+		scene Scene
+		want  bool
+	}{
+		{"Same render", white, true},
+		{"Different render", black, false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			res, err := h.Check(tC.scene)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := res.Passed(); got != tC.want {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+
+			if !tC.want {
+				if _, err := r.LoadCanvas(res.Diff); err != nil {
+					t.Errorf("Got %v loading the diff image, want it written", err)
+				}
+			}
+		})
+	}
+}
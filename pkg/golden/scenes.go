@@ -0,0 +1,212 @@
+package golden
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/fredrikln/the-ray-tracer-challenge-go/pkg/objparser"
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// Seed seeds the random source of every reference scene so renders are
+// repeatable
+const Seed = 1
+
+// Scene is a small scene with a fixed view. Tolerance is the largest mean
+// FLIP error allowed against the reference, with 0 meaning
+// DefaultTolerance.
+type Scene struct {
+	Name      string
+	Tolerance float64
+	Build     func() (*r.World, *r.Camera)
+}
+
+// Scenes is the catalog of reference scenes. Keep them small, every one is
+// rendered on each test run.
+var Scenes = []Scene{
+	{Name: "default-world", Build: defaultWorldScene},
+	{Name: "glass-spheres", Build: glassSpheresScene},
+	{Name: "csg", Build: csgScene},
+	{Name: "obj-triangles", Build: objTrianglesScene},
+	// Path traced noise makes the error between two correct renders larger
+	{Name: "path-traced", Tolerance: 0.1, Build: pathTracedScene},
+}
+
+func newWorld() *r.World {
+	w := r.NewWorld()
+	w.Source = rand.New(rand.NewSource(Seed))
+
+	return w
+}
+
+func newCamera(from, to r.Point) *r.Camera {
+	c := r.NewCamera(64, 48, math.Pi/3).SetIntegrator(r.NewWhitted())
+	c.SetTransform(r.ViewTransform(from, to, r.NewVec(0, 1, 0)))
+	c.Samples = 1
+	c.Depth = 5
+
+	return c
+}
+
+func checkeredFloor() *r.Plane {
+	floor := r.NewPlane()
+	floor.SetNewMaterial(r.NewMaterial().
+		SetPattern(r.NewCheckerPattern(r.NewColor(0.9, 0.9, 0.9), r.NewColor(0.2, 0.3, 0.4))).
+		SetSpecular(0).
+		SetReflective(0.1))
+	floor.SetTransform(r.NewTranslation(0, -1, 0))
+
+	return floor
+}
+
+// defaultWorldScene is the two spheres from the book
+func defaultWorldScene() (*r.World, *r.Camera) {
+	w := r.NewDefaultWorld()
+	w.Source = rand.New(rand.NewSource(Seed))
+
+	return w, newCamera(r.NewPoint(0, 0, -5), r.NewPoint(0, 0, 0))
+}
+
+// glassSpheresScene is a hollow glass sphere in front of a solid one on a
+// checkered floor, which exercises refraction, reflection and Fresnel
+func glassSpheresScene() (*r.World, *r.Camera) {
+	w := newWorld()
+	w.AddLight(r.NewPointLight(r.NewPoint(-10, 10, -10), r.NewColor(1, 1, 1)))
+	w.AddObject(checkeredFloor())
+
+	glass := r.NewMaterial().
+		SetColor(r.NewColor(0.1, 0.1, 0.1)).
+		SetDiffuse(0.1).
+		SetReflective(0.9).
+		SetTransparency(0.9).
+		SetRefractiveIndex(1.5)
+
+	outer := r.NewSphere()
+	outer.SetNewMaterial(glass)
+	outer.SetTransform(r.NewTranslation(-0.6, 0, 0))
+
+	air := r.NewMaterial().
+		SetColor(r.NewColor(0.1, 0.1, 0.1)).
+		SetDiffuse(0.1).
+		SetReflective(0.9).
+		SetTransparency(0.9).
+		SetRefractiveIndex(1.0000034)
+
+	inner := r.NewSphere()
+	inner.SetNewMaterial(air)
+	inner.SetTransform(r.NewTranslation(-0.6, 0, 0).Scale(0.5, 0.5, 0.5))
+
+	solid := r.NewSphere()
+	solid.SetNewMaterial(r.NewMaterial().SetColor(r.NewColor(0.8, 0.2, 0.1)))
+	solid.SetTransform(r.NewTranslation(1.2, -0.5, 2).Scale(0.5, 0.5, 0.5))
+
+	w.AddObject(outer)
+	w.AddObject(inner)
+	w.AddObject(solid)
+
+	return w, newCamera(r.NewPoint(0, 1.5, -5), r.NewPoint(0, 0, 0))
+}
+
+// csgScene is a cube with a sphere cut out of it next to the intersection
+// of a cube and a sphere
+func csgScene() (*r.World, *r.Camera) {
+	w := newWorld()
+	w.AddLight(r.NewPointLight(r.NewPoint(-10, 10, -10), r.NewColor(1, 1, 1)))
+	w.AddObject(checkeredFloor())
+
+	cube := r.NewCube()
+	cube.SetNewMaterial(r.NewMaterial().SetColor(r.NewColor(0.2, 0.6, 0.9)))
+
+	sphere := r.NewSphere()
+	sphere.SetNewMaterial(r.NewMaterial().SetColor(r.NewColor(0.9, 0.8, 0.2)))
+	sphere.SetTransform(r.NewTranslation(-0.5, 0.5, -0.5).Scale(1.1, 1.1, 1.1))
+
+	difference := r.NewCSG(r.Difference, cube, sphere)
+	difference.SetTransform(r.NewTranslation(-1.3, 0, 0).Scale(0.8, 0.8, 0.8).RotateY(math.Pi / 6))
+
+	rounding := r.NewSphere()
+	rounding.SetTransform(r.NewScaling(1.3, 1.3, 1.3))
+
+	lens := r.NewCSG(r.Intersect, r.NewCube(), rounding)
+	lens.SetTransform(r.NewTranslation(1.3, 0, 0).Scale(0.8, 0.8, 0.8).RotateY(-math.Pi / 5))
+
+	w.AddObject(difference)
+	w.AddObject(lens)
+
+	return w, newCamera(r.NewPoint(0, 2, -5), r.NewPoint(0, 0, 0))
+}
+
+// octahedron has flat and smooth shaded faces so both kinds of triangles
+// are covered
+const octahedron = `
+v 0 1.5 0
+v 1 0 0
+v 0 0 -1
+v -1 0 0
+v 0 0 1
+v 0 -1.5 0
+
+vn 0 1 0
+vn 1 0 0
+vn 0 0 -1
+vn -1 0 0
+vn 0 0 1
+vn 0 -1 0
+
+f 1 3 2
+f 1 4 3
+f 1 5 4
+f 1 2 5
+f 6//6 2//2 3//3
+f 6//6 3//3 4//4
+f 6//6 4//4 5//5
+f 6//6 5//5 2//2
+`
+
+// objTrianglesScene is an octahedron parsed from OBJ
+func objTrianglesScene() (*r.World, *r.Camera) {
+	w := newWorld()
+	w.AddLight(r.NewPointLight(r.NewPoint(-10, 10, -10), r.NewColor(1, 1, 1)))
+	w.AddObject(checkeredFloor())
+
+	p := objparser.NewParser()
+	p.SetNewMaterial(r.NewMaterial().SetColor(r.NewColor(0.7, 0.4, 0.8)))
+
+	g := p.Parse(octahedron)
+	g.SetTransform(r.NewTranslation(0, 0.5, 0).RotateY(math.Pi / 7))
+
+	w.AddObject(g)
+
+	return w, newCamera(r.NewPoint(0, 1.5, -4), r.NewPoint(0, 0.3, 0))
+}
+
+// pathTracedScene has a diffuse, a metal and a glass sphere under a sky
+// gradient, rendered with the path tracer
+func pathTracedScene() (*r.World, *r.Camera) {
+	w := newWorld()
+	w.SetBackground(r.NewSkyGradientBackground(), false)
+
+	floor := r.NewPlane()
+	floor.SetNewMaterial(r.NewDiffuse(r.NewColor(0.5, 0.5, 0.5)))
+	floor.SetTransform(r.NewTranslation(0, -1, 0))
+	w.AddObject(floor)
+
+	materials := []r.Scatters{
+		r.NewMetal(r.NewColor(0.8, 0.6, 0.2), 0.1),
+		r.NewDiffuse(r.NewColor(0.1, 0.2, 0.5)),
+		r.NewDielectric(1.5),
+	}
+
+	for i, m := range materials {
+		s := r.NewSphere()
+		s.SetNewMaterial(m)
+		s.SetTransform(r.NewTranslation(float64(i-1)*2.1, 0, 0))
+		w.AddObject(s)
+	}
+
+	c := newCamera(r.NewPoint(0, 1, -6), r.NewPoint(0, 0, 0)).SetIntegrator(r.NewPathTracer())
+	c.Samples = 32
+	c.Depth = 8
+
+	return w, c
+}
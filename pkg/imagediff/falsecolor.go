@@ -0,0 +1,36 @@
+package imagediff
+
+import (
+	"math"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// magma is a coarse version of the magma color map in sRGB, dark purple for
+// no error to pale yellow for the largest
+var magma = []r.Color{
+	r.NewColor(0.001, 0.000, 0.014),
+	r.NewColor(0.317, 0.071, 0.485),
+	r.NewColor(0.716, 0.215, 0.475),
+	r.NewColor(0.987, 0.536, 0.382),
+	r.NewColor(0.987, 0.991, 0.750),
+}
+
+// FalseColor maps the red channel of an error image in [0, 1] to colors,
+// which shows small differences much better than gray levels
+func FalseColor(errors *r.Canvas) *r.Canvas {
+	return errors.Remap(func(c r.Color) r.Color {
+		return falseColor(c.R)
+	})
+}
+
+func falseColor(e float64) r.Color {
+	t := clamp01(e) * float64(len(magma)-1)
+	i := int(math.Min(math.Floor(t), float64(len(magma)-2)))
+	f := t - float64(i)
+
+	c := magma[i].MulFloat(1 - f).Add(magma[i+1].MulFloat(f))
+
+	// Canvases are linear
+	return r.NewColor(r.SRGB.Decode(c.R), r.SRGB.Decode(c.G), r.SRGB.Decode(c.B))
+}
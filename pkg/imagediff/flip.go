@@ -0,0 +1,289 @@
+package imagediff
+
+import (
+	"math"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// Constants of LDR-FLIP from "FLIP: A Difference Evaluator for Alternating
+// Images" by Andersson et al.
+const (
+	flipQc = 0.7
+	flipPc = 0.4
+	flipPt = 0.95
+	flipQf = 0.5
+	// flipFeatureWidth is the width in degrees of the edges and points
+	// feature detection looks for
+	flipFeatureWidth = 0.082
+)
+
+// D65 white point of linear sRGB in XYZ
+const (
+	whiteX = 0.950428545
+	whiteY = 1.0
+	whiteZ = 1.088900371
+)
+
+// flipCSF is the contrast sensitivity of one opponent channel as a sum of
+// two Gaussians, a1*sqrt(π/b1)*exp(-π²x²/b1) plus the same with a2 and b2,
+// for x in degrees
+type flipCSF struct {
+	a1, b1, a2, b2 float64
+}
+
+var (
+	flipAchromatic = flipCSF{1, 0.0047, 0, 1e-5}
+	flipRedGreen   = flipCSF{1, 0.0053, 0, 1e-5}
+	flipBlueYellow = flipCSF{34.1, 0.04, 13.5, 0.025}
+)
+
+// FLIP is the mean LDR-FLIP error between two images, along with the error
+// of every pixel, from 0 for no visible difference to 1. Both images are
+// clamped to [0, 1] first. ppd is the number of pixels per degree of visual
+// angle the images are seen at.
+//
+// Colors are filtered by the contrast sensitivity of each opponent channel
+// and compared in a perceptually uniform space, then differences in edges
+// and points are added on top, since those are noticed even where colors
+// are close.
+func FLIP(reference, test *r.Canvas, ppd float64) (float64, *r.Canvas, error) {
+	if err := checkSize(reference, test); err != nil {
+		return 0, nil, err
+	}
+
+	if ppd <= 0 {
+		ppd = DefaultPixelsPerDegree
+	}
+
+	referenceYCxCz := toYCxCz(reference)
+	testYCxCz := toYCxCz(test)
+
+	colorError := flipColorError(filterYCxCz(referenceYCxCz, ppd), filterYCxCz(testYCxCz, ppd))
+	featureError := flipFeatureError(referenceYCxCz[0], testYCxCz[0], ppd)
+
+	errorMap := r.NewCanvas(reference.Width, reference.Height)
+	var sum float64
+
+	for i, c := range colorError.values {
+		e := math.Pow(c, 1-featureError.values[i])
+
+		errorMap.Pixels[i] = r.NewColor(e, e, e)
+		sum += e
+	}
+
+	return sum / float64(len(colorError.values)), errorMap, nil
+}
+
+// toYCxCz converts clamped linear sRGB to the opponent space FLIP filters in
+func toYCxCz(c *r.Canvas) [3]*plane {
+	var out [3]*plane
+
+	for i := range out {
+		out[i] = newPlane(c.Width, c.Height)
+	}
+
+	for i, p := range c.Pixels {
+		x, y, z := linearRGBToXYZ(clamp01(p.R), clamp01(p.G), clamp01(p.B))
+
+		out[0].values[i] = 116*y/whiteY - 16
+		out[1].values[i] = 500 * (x/whiteX - y/whiteY)
+		out[2].values[i] = 200 * (y/whiteY - z/whiteZ)
+	}
+
+	return out
+}
+
+func filterYCxCz(ycxcz [3]*plane, ppd float64) [3]*plane {
+	return [3]*plane{
+		flipAchromatic.filter(ycxcz[0], ppd),
+		flipRedGreen.filter(ycxcz[1], ppd),
+		flipBlueYellow.filter(ycxcz[2], ppd),
+	}
+}
+
+// filter convolves with the 2D form of the CSF. Each Gaussian is separable,
+// so they are applied one at a time and mixed by their share of the total
+// weight.
+func (csf flipCSF) filter(p *plane, ppd float64) *plane {
+	radius := int(math.Ceil(3 * math.Sqrt(math.Max(csf.b1, csf.b2)/(2*math.Pi*math.Pi)) * ppd))
+
+	gaussian := func(b float64) ([]float64, float64) {
+		kernel := make([]float64, 2*radius+1)
+		var sum float64
+
+		for i := range kernel {
+			x := float64(i-radius) / ppd
+			kernel[i] = math.Sqrt(math.Pi/b) * math.Exp(-math.Pi*math.Pi*x*x/b)
+			sum += kernel[i]
+		}
+
+		for i := range kernel {
+			kernel[i] /= sum
+		}
+
+		return kernel, sum
+	}
+
+	kernel1, sum1 := gaussian(csf.b1)
+	filtered := p.convolve(kernel1, kernel1)
+
+	if csf.a2 == 0 {
+		return filtered
+	}
+
+	kernel2, sum2 := gaussian(csf.b2)
+	weight1, weight2 := csf.a1*sum1*sum1, csf.a2*sum2*sum2
+	share := weight1 / (weight1 + weight2)
+
+	return filtered.combine(p.convolve(kernel2, kernel2), func(a, b float64) float64 {
+		return share*a + (1-share)*b
+	})
+}
+
+// flipColorError compares filtered colors with the HyAB distance in a Hunt
+// adjusted L*a*b* space and remaps it to [0, 1], spending most of the range
+// on small differences
+func flipColorError(reference, test [3]*plane) *plane {
+	maxError := math.Pow(hyAB(huntLab(0, 1, 0), huntLab(0, 0, 1)), flipQc)
+
+	out := newPlane(reference[0].width, reference[0].height)
+
+	for i := range out.values {
+		a := huntLab(ycxczToLinearRGB(reference[0].values[i], reference[1].values[i], reference[2].values[i]))
+		b := huntLab(ycxczToLinearRGB(test[0].values[i], test[1].values[i], test[2].values[i]))
+
+		e := math.Pow(hyAB(a, b), flipQc)
+
+		if e < flipPc*maxError {
+			e = flipPt / (flipPc * maxError) * e
+		} else {
+			e = flipPt + (e-flipPc*maxError)/(maxError-flipPc*maxError)*(1-flipPt)
+		}
+
+		out.values[i] = math.Min(e, 1)
+	}
+
+	return out
+}
+
+// flipFeatureError compares the strength of edges and points in the
+// achromatic channel
+func flipFeatureError(reference, test *plane, ppd float64) *plane {
+	sigma := 0.5 * flipFeatureWidth * ppd
+	radius := int(math.Ceil(3 * sigma))
+
+	gaussian := make([]float64, 2*radius+1)
+	edge := make([]float64, 2*radius+1)
+	point := make([]float64, 2*radius+1)
+
+	for i := range gaussian {
+		x := float64(i - radius)
+		g := math.Exp(-x * x / (2 * sigma * sigma))
+
+		gaussian[i] = g
+		edge[i] = -x * g
+		point[i] = (x*x/(sigma*sigma) - 1) * g
+	}
+
+	normalize(gaussian)
+	normalize(edge)
+	normalize(point)
+
+	features := func(p *plane) (*plane, *plane) {
+		// Features are found in luminance scaled to [0, 1]
+		y := newPlane(p.width, p.height)
+		for i, v := range p.values {
+			y.values[i] = (v + 16) / 116
+		}
+
+		magnitude := func(kernel []float64) *plane {
+			return y.convolve(kernel, gaussian).combine(y.convolve(gaussian, kernel), math.Hypot)
+		}
+
+		return magnitude(edge), magnitude(point)
+	}
+
+	referenceEdges, referencePoints := features(reference)
+	testEdges, testPoints := features(test)
+
+	out := newPlane(reference.width, reference.height)
+
+	for i := range out.values {
+		edgeDifference := math.Abs(referenceEdges.values[i] - testEdges.values[i])
+		pointDifference := math.Abs(referencePoints.values[i] - testPoints.values[i])
+
+		out.values[i] = math.Pow(math.Max(edgeDifference, pointDifference)/math.Sqrt2, flipQf)
+	}
+
+	return out
+}
+
+// normalize scales the positive weights of a kernel to sum to 1 and the
+// negative ones to sum to -1
+func normalize(kernel []float64) {
+	var positive, negative float64
+
+	for _, w := range kernel {
+		if w > 0 {
+			positive += w
+		} else {
+			negative -= w
+		}
+	}
+
+	for i, w := range kernel {
+		if w > 0 {
+			kernel[i] = w / positive
+		} else if negative > 0 {
+			kernel[i] = w / negative
+		}
+	}
+}
+
+func linearRGBToXYZ(red, green, blue float64) (float64, float64, float64) {
+	return 0.4124564*red + 0.3575761*green + 0.1804375*blue,
+		0.2126729*red + 0.7151522*green + 0.0721750*blue,
+		0.0193339*red + 0.1191920*green + 0.9503041*blue
+}
+
+func ycxczToLinearRGB(yy, cx, cz float64) (float64, float64, float64) {
+	y := (yy + 16) / 116
+	x := whiteX * (cx/500 + y)
+	z := whiteZ * (y - cz/200)
+	y *= whiteY
+
+	red := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	green := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	blue := 0.0556434*x - 0.2040259*y + 1.0572252*z
+
+	return clamp01(red), clamp01(green), clamp01(blue)
+}
+
+// huntLab converts linear sRGB to L*a*b* with the chroma scaled down in
+// dark colors, where it is harder to see
+func huntLab(red, green, blue float64) [3]float64 {
+	x, y, z := linearRGBToXYZ(red, green, blue)
+
+	f := func(t float64) float64 {
+		const delta = 6.0 / 29
+
+		if t > delta*delta*delta {
+			return math.Cbrt(t)
+		}
+
+		return t/(3*delta*delta) + 4.0/29
+	}
+
+	fx, fy, fz := f(x/whiteX), f(y/whiteY), f(z/whiteZ)
+	l := 116*fy - 16
+
+	return [3]float64{l, 0.01 * l * 500 * (fx - fy), 0.01 * l * 200 * (fy - fz)}
+}
+
+// hyAB is the sum of the lightness difference and the Euclidean chroma
+// difference, which works better than the plain Euclidean distance for
+// large differences
+func hyAB(a, b [3]float64) float64 {
+	return math.Abs(a[0]-b[0]) + math.Hypot(a[1]-b[1], a[2]-b[2])
+}
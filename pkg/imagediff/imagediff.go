@@ -0,0 +1,117 @@
+package imagediff
+
+import (
+	"fmt"
+	"math"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// DefaultPixelsPerDegree is a 0.7 m wide 4K monitor seen from 0.7 m, the
+// viewing condition FLIP is usually reported for
+const DefaultPixelsPerDegree = 67.0
+
+// Result holds every metric for a test image compared to a reference.
+// FLIPMap is the per-pixel FLIP error in every channel.
+type Result struct {
+	RMSE    float64
+	PSNR    float64
+	SSIM    float64
+	FLIP    float64
+	FLIPMap *r.Canvas
+}
+
+// Compare computes every metric for test against reference. ppd is the
+// number of pixels per degree of visual angle, which FLIP needs to model
+// how visible differences are.
+func Compare(reference, test *r.Canvas, ppd float64) (*Result, error) {
+	rmse, err := RMSE(reference, test)
+	if err != nil {
+		return nil, err
+	}
+
+	ssim, err := SSIM(reference, test)
+	if err != nil {
+		return nil, err
+	}
+
+	flip, flipMap, err := FLIP(reference, test, ppd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		RMSE:    rmse,
+		PSNR:    psnr(rmse),
+		SSIM:    ssim,
+		FLIP:    flip,
+		FLIPMap: flipMap,
+	}, nil
+}
+
+// CompareFiles loads two images with r.LoadCanvas and compares them
+func CompareFiles(referenceFile, testFile string, ppd float64) (*Result, error) {
+	reference, err := r.LoadCanvas(referenceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	test, err := r.LoadCanvas(testFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return Compare(reference, test, ppd)
+}
+
+// DiffImage shows the FLIP error of every pixel in false color
+func (res *Result) DiffImage() *r.Canvas {
+	return FalseColor(res.FLIPMap)
+}
+
+func (res *Result) String() string {
+	return fmt.Sprintf("RMSE: %.6f\nPSNR: %.2f dB\nSSIM: %.6f\nFLIP: %.6f", res.RMSE, res.PSNR, res.SSIM, res.FLIP)
+}
+
+// RMSE is the root mean square difference of the linear color channels
+func RMSE(a, b *r.Canvas) (float64, error) {
+	if err := checkSize(a, b); err != nil {
+		return 0, err
+	}
+
+	var sum float64
+
+	for i, p := range a.Pixels {
+		d := p.Sub(b.Pixels[i])
+		sum += d.R*d.R + d.G*d.G + d.B*d.B
+	}
+
+	return math.Sqrt(sum / float64(3*len(a.Pixels))), nil
+}
+
+// PSNR is the peak signal to noise ratio in decibels with a peak of 1.
+// Identical images have an infinite PSNR.
+func PSNR(a, b *r.Canvas) (float64, error) {
+	rmse, err := RMSE(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return psnr(rmse), nil
+}
+
+func psnr(rmse float64) float64 {
+	if rmse == 0 {
+		return math.Inf(1)
+	}
+
+	return -20 * math.Log10(rmse)
+}
+
+func checkSize(a, b *r.Canvas) error {
+	if a.Width != b.Width || a.Height != b.Height {
+		return fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package imagediff
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+func uniform(width, height int, c r.Color) *r.Canvas {
+	canvas := r.NewCanvas(width, height)
+
+	for i := range canvas.Pixels {
+		canvas.Pixels[i] = c
+	}
+
+	return canvas
+}
+
+// gradient is a smooth test image with some structure for SSIM and FLIP
+func gradient() *r.Canvas {
+	canvas := r.NewCanvas(32, 32)
+
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := float64(x) / 31
+			if (x/8+y/8)%2 == 0 {
+				v *= 0.5
+			}
+
+			canvas.SetPixel(x, y, r.NewColor(v, float64(y)/31, 0.25))
+		}
+	}
+
+	return canvas
+}
+
+func withNoise(c *r.Canvas, amount float64, seed int64) *r.Canvas {
+	source := rand.New(rand.NewSource(seed))
+
+	return c.Remap(func(p r.Color) r.Color {
+		return p.Add(r.NewColor(
+			(source.Float64()-0.5)*amount,
+			(source.Float64()-0.5)*amount,
+			(source.Float64()-0.5)*amount,
+		))
+	})
+}
+
+func TestCompareIdentical(t *testing.T) {
+	img := gradient()
+
+	res, err := Compare(img, img, DefaultPixelsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc string
+		got  float64
+		want float64
+	}{
+		{"RMSE", res.RMSE, 0},
+		{"PSNR", res.PSNR, math.Inf(1)},
+		{"SSIM", res.SSIM, 1},
+		{"FLIP", res.FLIP, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if math.Abs(tC.got-tC.want) > 1e-9 && tC.got != tC.want {
+				t.Errorf("Got %v, want %v", tC.got, tC.want)
+			}
+		})
+	}
+}
+
+func TestRMSEAndPSNR(t *testing.T) {
+	a := uniform(4, 4, r.NewColor(0.5, 0.5, 0.5))
+	b := uniform(4, 4, r.NewColor(0.6, 0.4, 0.6))
+
+	rmse, err := RMSE(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(rmse-0.1) > 1e-9 {
+		t.Errorf("Got %v, want %v", rmse, 0.1)
+	}
+
+	psnr, _ := PSNR(a, b)
+	if math.Abs(psnr-20) > 1e-6 {
+		t.Errorf("Got %v, want %v", psnr, 20)
+	}
+}
+
+func TestMetricsGetWorseWithMoreNoise(t *testing.T) {
+	img := gradient()
+
+	small, err := Compare(img, withNoise(img, 0.05, 1), DefaultPixelsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large, err := Compare(img, withNoise(img, 0.4, 1), DefaultPixelsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc         string
+		small, large float64
+		higherIsBad  bool
+	}{
+		{"RMSE", small.RMSE, large.RMSE, true},
+		{"PSNR", small.PSNR, large.PSNR, false},
+		{"SSIM", small.SSIM, large.SSIM, false},
+		{"FLIP", small.FLIP, large.FLIP, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if tC.higherIsBad != (tC.large > tC.small) {
+				t.Errorf("Got %v with little noise and %v with more", tC.small, tC.large)
+			}
+		})
+	}
+
+	if small.SSIM >= 1 || small.FLIP <= 0 {
+		t.Errorf("Got SSIM %v and FLIP %v, want a visible difference", small.SSIM, small.FLIP)
+	}
+}
+
+func TestFLIPBlackAndWhite(t *testing.T) {
+	flip, errorMap, err := FLIP(uniform(8, 8, r.NewColor(0, 0, 0)), uniform(8, 8, r.NewColor(1, 1, 1)), DefaultPixelsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flip < 0.9 || flip > 1 {
+		t.Errorf("Got %v, want close to 1", flip)
+	}
+
+	if errorMap.Width != 8 || errorMap.Height != 8 {
+		t.Errorf("Got %dx%d error map, want 8x8", errorMap.Width, errorMap.Height)
+	}
+}
+
+func TestCompareSizeMismatch(t *testing.T) {
+	if _, err := Compare(r.NewCanvas(2, 2), r.NewCanvas(2, 3), DefaultPixelsPerDegree); err == nil {
+		t.Errorf("Got no error, want one")
+	}
+}
+
+func TestFalseColor(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		error float64
+		want  r.Color
+	}{
+		{"No error", 0, r.NewColor(r.SRGB.Decode(0.001), 0, r.SRGB.Decode(0.014))},
+		{"Largest error", 1, r.NewColor(r.SRGB.Decode(0.987), r.SRGB.Decode(0.991), r.SRGB.Decode(0.750))},
+		{"Clamped", 2, r.NewColor(r.SRGB.Decode(0.987), r.SRGB.Decode(0.991), r.SRGB.Decode(0.750))},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := FalseColor(uniform(1, 1, r.NewColor(tC.error, tC.error, tC.error))).Pixels[0]
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	dir := t.TempDir()
+	img := gradient()
+
+	reference, test := filepath.Join(dir, "reference.pfm"), filepath.Join(dir, "test.png")
+
+	if err := img.SavePFM(reference); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := img.SavePNG(test); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := CompareFiles(reference, test, DefaultPixelsPerDegree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 8-bit quantization is almost invisible
+	if res.FLIP > 0.05 || res.SSIM < 0.99 {
+		t.Errorf("Got FLIP %v and SSIM %v, want almost identical images", res.FLIP, res.SSIM)
+	}
+
+	if diff := res.DiffImage(); diff.Width != img.Width || diff.Height != img.Height {
+		t.Errorf("Got %dx%d diff image, want %dx%d", diff.Width, diff.Height, img.Width, img.Height)
+	}
+}
@@ -0,0 +1,114 @@
+package imagediff
+
+import (
+	"math"
+
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// plane is a single channel image
+type plane struct {
+	width, height int
+	values        []float64
+}
+
+func newPlane(width, height int) *plane {
+	return &plane{width, height, make([]float64, width*height)}
+}
+
+// planeFrom builds a plane by applying f to every pixel of a canvas
+func planeFrom(c *r.Canvas, f func(r.Color) float64) *plane {
+	p := newPlane(c.Width, c.Height)
+
+	for i, color := range c.Pixels {
+		p.values[i] = f(color)
+	}
+
+	return p
+}
+
+func (p *plane) at(x, y int) float64 {
+	return p.values[y*p.width+x]
+}
+
+// convolve filters the plane with a separable kernel, horizontal along rows
+// and vertical along columns, repeating the edge pixels outside the image
+func (p *plane) convolve(horizontal, vertical []float64) *plane {
+	return p.convolve1D(horizontal, true).convolve1D(vertical, false)
+}
+
+func (p *plane) convolve1D(kernel []float64, horizontal bool) *plane {
+	out := newPlane(p.width, p.height)
+	radius := len(kernel) / 2
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			var sum float64
+
+			for k, w := range kernel {
+				if horizontal {
+					sum += w * p.at(clamp(x+k-radius, p.width), y)
+				} else {
+					sum += w * p.at(x, clamp(y+k-radius, p.height))
+				}
+			}
+
+			out.values[y*p.width+x] = sum
+		}
+	}
+
+	return out
+}
+
+// combine applies f to matching values of two planes
+func (p *plane) combine(q *plane, f func(a, b float64) float64) *plane {
+	out := newPlane(p.width, p.height)
+
+	for i := range out.values {
+		out.values[i] = f(p.values[i], q.values[i])
+	}
+
+	return out
+}
+
+func (p *plane) mean() float64 {
+	var sum float64
+
+	for _, v := range p.values {
+		sum += v
+	}
+
+	return sum / float64(len(p.values))
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+
+	if i >= n {
+		return n - 1
+	}
+
+	return i
+}
+
+// gaussianKernel samples a normalized Gaussian out to three standard
+// deviations
+func gaussianKernel(sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+
+	var sum float64
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	return kernel
+}
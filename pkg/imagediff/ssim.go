@@ -0,0 +1,68 @@
+package imagediff
+
+import (
+	r "github.com/fredrikln/the-ray-tracer-challenge-go/pkg/raytracer"
+)
+
+// Stabilizing constants of SSIM for values in [0, 1]
+const (
+	ssimC1 = 0.01 * 0.01
+	ssimC2 = 0.03 * 0.03
+)
+
+// SSIM is the mean structural similarity of the display luma of two images,
+// with the usual 11x11 Gaussian window of standard deviation 1.5. It is 1
+// for identical images.
+func SSIM(a, b *r.Canvas) (float64, error) {
+	if err := checkSize(a, b); err != nil {
+		return 0, err
+	}
+
+	x, y := planeFrom(a, displayLuma), planeFrom(b, displayLuma)
+	window := gaussianKernel(1.5)
+
+	blur := func(p *plane) *plane {
+		return p.convolve(window, window)
+	}
+
+	multiply := func(a, b float64) float64 { return a * b }
+
+	muX, muY := blur(x), blur(y)
+	xx, yy, xy := blur(x.combine(x, multiply)), blur(y.combine(y, multiply)), blur(x.combine(y, multiply))
+
+	ssim := newPlane(x.width, x.height)
+
+	for i := range ssim.values {
+		mx, my := muX.values[i], muY.values[i]
+		varianceX := xx.values[i] - mx*mx
+		varianceY := yy.values[i] - my*my
+		covariance := xy.values[i] - mx*my
+
+		ssim.values[i] = ((2*mx*my + ssimC1) * (2*covariance + ssimC2)) /
+			((mx*mx + my*my + ssimC1) * (varianceX + varianceY + ssimC2))
+	}
+
+	return ssim.mean(), nil
+}
+
+// displayLuma is the Rec. 709 luma of a color clamped and sRGB encoded as
+// it would be shown on screen
+func displayLuma(c r.Color) float64 {
+	encode := func(v float64) float64 {
+		return r.SRGB.Encode(clamp01(v))
+	}
+
+	return 0.2126*encode(c.R) + 0.7152*encode(c.G) + 0.0722*encode(c.B)
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+
+	if v > 1 {
+		return 1
+	}
+
+	return v
+}
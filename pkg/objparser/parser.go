@@ -21,7 +21,7 @@ func NewParser() *Objparser {
 		vertices:        make([]r.Point, 1),
 		normals:         make([]r.Vec, 1),
 		numIgnoredLines: 0,
-		defaultGroup:    r.NewGroup(),
+		defaultGroup:    r.NewGroup().SetSolid(true),
 	}
 }
 
@@ -31,6 +31,8 @@ func (p *Objparser) SetNewMaterial(m r.Scatters) *Objparser {
 	return p
 }
 
+// Parse returns the mesh as one group, marked Solid so refraction treats it
+// as a single closed object wherever it is placed in a scene
 func (p *Objparser) Parse(input string) *r.Group {
 	lines := strings.Split(input, "\n")
 
@@ -182,6 +182,10 @@ func TestNamedGroups(t *testing.T) {
 	if !t2.(*r.Triangle).P3.Eq(parser.vertices[4]) {
 		t.Error("T2P3 wrong vertex")
 	}
+
+	if !g.Solid || g1.(*r.Group).Solid {
+		t.Error("Only the mesh group should be solid")
+	}
 }
 
 func TestSmoothTrianglesInObj(t *testing.T) {
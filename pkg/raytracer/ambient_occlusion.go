@@ -0,0 +1,76 @@
+package raytracer
+
+// AmbientOcclusion shades each camera hit by the fraction of hemisphere rays
+// that travel Distance without hitting anything. It ignores materials and
+// lights, which makes it useful for reviewing shapes.
+type AmbientOcclusion struct {
+	Samples  int
+	Distance float64
+}
+
+func NewAmbientOcclusion(samples int, distance float64) *AmbientOcclusion {
+	return &AmbientOcclusion{
+		Samples:  samples,
+		Distance: distance,
+	}
+}
+
+func (ao *AmbientOcclusion) ColorAt(w *World, r *Ray, remaining int) Color {
+	color, _ := ao.colorAt(w, r)
+
+	return color
+}
+
+// ColorAndCoverageAt is ColorAt for a transparent camera, where every camera
+// ray that hits an object covers the pixel
+func (ao *AmbientOcclusion) ColorAndCoverageAt(w *World, r *Ray, remaining int) (Color, float64) {
+	color, escaped := ao.colorAt(w, r)
+
+	if escaped {
+		return colorBlack, 0
+	}
+
+	return color, 1
+}
+
+// colorAt returns the visibility at the hit and whether the ray missed
+func (ao *AmbientOcclusion) colorAt(w *World, r *Ray) (Color, bool) {
+	xs := w.Intersect(*r)
+	hit, didHit := GetHit(xs)
+
+	if !didHit {
+		return w.CameraMissColor(r), true
+	}
+
+	comps := PrepareComputationsWithHit(hit, *r, xs)
+	visibility := 1 - ao.Occlusion(w, comps)
+
+	return NewColor(visibility, visibility, visibility), false
+}
+
+// Occlusion returns the fraction of cosine weighted hemisphere rays around
+// the hit normal that are blocked within Distance
+func (ao *AmbientOcclusion) Occlusion(w *World, comps *Computations) float64 {
+	if ao.Samples <= 0 {
+		return 0
+	}
+
+	var occluded int
+
+	for i := 0; i < ao.Samples; i++ {
+		direction := comps.Normalv.Add(RandomInUnitSphere(w.Source))
+
+		if direction.NearZero() {
+			direction = comps.Normalv
+		}
+
+		ray := NewRay(comps.OverPoint, direction.Norm())
+		hit, didHit := GetHit(w.Intersect(ray))
+
+		if didHit && hit.Time < ao.Distance {
+			occluded++
+		}
+	}
+
+	return float64(occluded) / float64(ao.Samples)
+}
@@ -0,0 +1,91 @@
+package raytracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAmbientOcclusionUnoccludedPlane(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(NewPlane())
+
+	r := NewRay(NewPoint(0, 1, -1), NewVec(0, -1, 1).Norm())
+
+	got := NewAmbientOcclusion(16, 10).ColorAt(w, &r, 1)
+	want := NewColor(1, 1, 1)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestAmbientOcclusionInsideClosedObject(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(NewSphere().SetTransform(NewScaling(10, 10, 10)))
+
+	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
+
+	testCases := []struct {
+		desc     string
+		distance float64
+		want     Color
+	}{
+		{
+			desc:     "Within distance",
+			distance: 100,
+			want:     NewColor(0, 0, 0),
+		},
+		{
+			desc:     "Zero distance",
+			distance: 0,
+			want:     NewColor(1, 1, 1),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := NewAmbientOcclusion(16, tC.distance).ColorAt(w, &r, 1)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestAmbientOcclusionMissUsesBackground(t *testing.T) {
+	w := NewWorld()
+	background := NewColor(0.2, 0.3, 0.4)
+	w.Background = NewConstantBackground(background)
+
+	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
+
+	got := NewAmbientOcclusion(16, 1).ColorAt(w, &r, 1)
+
+	if !got.Eq(background) {
+		t.Errorf("Got %v, want %v", got, background)
+	}
+}
+
+func TestRenderAmbientOcclusionBuffer(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(NewSphere().SetTransform(NewScaling(10, 10, 10)))
+
+	c := NewCamera(5, 4, math.Pi/2)
+	c.Samples = 1
+
+	canvas := c.RenderAmbientOcclusion(w, NewAmbientOcclusion(4, 100))
+
+	if canvas.Width != 5 || canvas.Height != 4 {
+		t.Errorf("Got %vx%v, want %vx%v", canvas.Width, canvas.Height, 5, 4)
+	}
+
+	for i, pixel := range canvas.Pixels {
+		if !pixel.Eq(NewColor(0, 0, 0)) {
+			t.Errorf("Pixel %d, got %v, want %v", i, pixel, NewColor(0, 0, 0))
+		}
+	}
+
+	if _, ok := c.Integrator.(*PathTracer); !ok {
+		t.Error("Camera integrator should not change")
+	}
+}
@@ -0,0 +1,49 @@
+package raytracer
+
+// Background is the radiance arriving along rays that leave the scene
+// without hitting anything. Backgrounds that can also be sampled for direct
+// lighting implement Environment.
+type Background interface {
+	ColorAt(direction Vec) Color
+}
+
+// ConstantBackground has the same color in every direction
+type ConstantBackground struct {
+	Color Color
+}
+
+func NewConstantBackground(c Color) *ConstantBackground {
+	return &ConstantBackground{
+		Color: c,
+	}
+}
+
+func (cb *ConstantBackground) ColorAt(direction Vec) Color {
+	return cb.Color
+}
+
+// GradientBackground blends vertically from Bottom straight down to Top
+// straight up, which makes a cheap sky
+type GradientBackground struct {
+	Bottom Color
+	Top    Color
+}
+
+func NewGradientBackground(bottom, top Color) *GradientBackground {
+	return &GradientBackground{
+		Bottom: bottom,
+		Top:    top,
+	}
+}
+
+// NewSkyGradientBackground returns the white to light blue gradient from
+// Ray Tracing in One Weekend
+func NewSkyGradientBackground() *GradientBackground {
+	return NewGradientBackground(NewColor(1, 1, 1), NewColor(0.5, 0.7, 1.0))
+}
+
+func (gb *GradientBackground) ColorAt(direction Vec) Color {
+	t := 0.5 * (direction.Norm().Y + 1.0)
+
+	return gb.Bottom.MulFloat(1 - t).Add(gb.Top.MulFloat(t))
+}
@@ -0,0 +1,88 @@
+package raytracer
+
+import (
+	"testing"
+)
+
+func TestBackgroundColorAt(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		background Background
+		dir        Vec
+		want       Color
+	}{
+		{
+			desc:       "Constant",
+			background: NewConstantBackground(NewColor(0.1, 0.2, 0.3)),
+			dir:        NewVec(0, 1, 0),
+			want:       NewColor(0.1, 0.2, 0.3),
+		},
+		{
+			desc:       "Gradient straight up",
+			background: NewSkyGradientBackground(),
+			dir:        NewVec(0, 2, 0),
+			want:       NewColor(0.5, 0.7, 1.0),
+		},
+		{
+			desc:       "Gradient straight down",
+			background: NewSkyGradientBackground(),
+			dir:        NewVec(0, -1, 0),
+			want:       NewColor(1, 1, 1),
+		},
+		{
+			desc:       "Gradient at the horizon",
+			background: NewGradientBackground(NewColor(0, 0, 0), NewColor(1, 1, 1)),
+			dir:        NewVec(1, 0, 0),
+			want:       NewColor(0.5, 0.5, 0.5),
+		},
+		{
+			desc:       "Image",
+			background: NewEnvironmentMap(uniformCanvas(4, 2, NewColor(0.7, 0.7, 0.7))),
+			dir:        NewVec(0, 0, 1),
+			want:       NewColor(0.7, 0.7, 0.7),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := tC.background.ColorAt(tC.dir)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestHiddenBackgroundIsBlackToCamera(t *testing.T) {
+	w := NewWorld()
+	w.SetBackground(NewConstantBackground(NewColor(1, 1, 1)), true)
+
+	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
+
+	integrators := []Integrator{NewPathTracer(), NewWhitted(), NewAmbientOcclusion(4, 1)}
+
+	for _, integrator := range integrators {
+		got := integrator.ColorAt(w, &r, 4)
+
+		if !got.Eq(NewColor(0, 0, 0)) {
+			t.Errorf("Got %v, want %v", got, NewColor(0, 0, 0))
+		}
+	}
+}
+
+func TestHiddenBackgroundStillLightsReflections(t *testing.T) {
+	w := NewWorld()
+	w.SetBackground(NewConstantBackground(NewColor(1, 1, 1)), true)
+
+	mirror := NewPlane()
+	mirror.SetNewMaterial(NewMaterial().SetColor(NewColor(0, 0, 0)).SetAmbient(0).SetDiffuse(0).SetSpecular(0).SetReflective(1))
+	w.AddObject(mirror)
+
+	r := NewRay(NewPoint(0, 1, 0), NewVec(0, -1, 0))
+
+	got := NewWhitted().ColorAt(w, &r, 4)
+
+	if !got.Eq(NewColor(1, 1, 1)) {
+		t.Errorf("Got %v, want %v", got, NewColor(1, 1, 1))
+	}
+}
@@ -85,14 +85,22 @@ func (bb *BoundingBox) Transform(m *Matrix) *BoundingBox {
 }
 
 func (bb *BoundingBox) Intersect(localRay Ray) bool {
+	tmin, tmax := bb.IntersectRange(localRay)
+
+	return tmin <= tmax
+}
+
+// IntersectRange returns the t where a ray enters and leaves the box, with
+// tmin greater than tmax when it misses
+func (bb *BoundingBox) IntersectRange(localRay Ray) (tmin, tmax float64) {
 	xtmin, xtmax := bbCheckAxis(localRay.Origin.X, localRay.Direction.X, bb.Minimum.X, bb.Maximum.X)
 	ytmin, ytmax := bbCheckAxis(localRay.Origin.Y, localRay.Direction.Y, bb.Minimum.Y, bb.Maximum.Y)
 	ztmin, ztmax := bbCheckAxis(localRay.Origin.Z, localRay.Direction.Z, bb.Minimum.Z, bb.Maximum.Z)
 
-	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
-	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
+	tmin = math.Max(math.Max(xtmin, ytmin), ztmin)
+	tmax = math.Min(math.Min(xtmax, ytmax), ztmax)
 
-	return tmin <= tmax
+	return tmin, tmax
 }
 
 func bbCheckAxis(origin, direction, min, max float64) (float64, float64) {
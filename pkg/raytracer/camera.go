@@ -3,21 +3,31 @@ package raytracer
 import (
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 )
 
 type Camera struct {
-	Hsize           int
-	Vsize           int
-	Fov             float64
-	Transform       *Matrix
-	PixelSize       float64
-	HalfWidth       float64
-	HalfHeight      float64
-	Samples         int
-	Depth           int
-	GammaCorrection bool
+	Hsize      int
+	Vsize      int
+	Fov        float64
+	Transform  *Matrix
+	PixelSize  float64
+	HalfWidth  float64
+	HalfHeight float64
+	Samples    int
+	Depth      int
+	Integrator Integrator
+	// MedianOfMeans splits the samples of a pixel into this many groups and
+	// uses the median of their means, which rejects rare bright outliers.
+	// Values below 2 use the plain mean.
+	MedianOfMeans int
+	// Transparent makes camera rays that escape to the background
+	// transparent instead of showing it, so the beauty canvas gets an alpha
+	// channel with the coverage of each pixel. The integrator decides what
+	// covers a pixel, see CoverageIntegrator.
+	Transparent bool
 }
 
 func NewCamera(hsize, vsize int, fov float64) *Camera {
@@ -35,16 +45,16 @@ func NewCamera(hsize, vsize int, fov float64) *Camera {
 	}
 
 	return &Camera{
-		Hsize:           hsize,
-		Vsize:           vsize,
-		Fov:             fov,
-		Transform:       NewIdentityMatrix(),
-		PixelSize:       (halfWidth * 2) / float64(hsize),
-		HalfWidth:       halfWidth,
-		HalfHeight:      halfHeight,
-		Samples:         10,
-		Depth:           8,
-		GammaCorrection: false,
+		Hsize:      hsize,
+		Vsize:      vsize,
+		Fov:        fov,
+		Transform:  NewIdentityMatrix(),
+		PixelSize:  (halfWidth * 2) / float64(hsize),
+		HalfWidth:  halfWidth,
+		HalfHeight: halfHeight,
+		Samples:    10,
+		Depth:      8,
+		Integrator: NewPathTracer(),
 	}
 }
 
@@ -69,8 +79,47 @@ func (c *Camera) SetTransform(m *Matrix) *Camera {
 	return c
 }
 
+func (c *Camera) SetIntegrator(i Integrator) *Camera {
+	c.Integrator = i
+
+	return c
+}
+
+func (c *Camera) SetMedianOfMeans(groups int) *Camera {
+	c.MedianOfMeans = groups
+
+	return c
+}
+
+func (c *Camera) SetTransparent(transparent bool) *Camera {
+	c.Transparent = transparent
+
+	return c
+}
+
 func (c *Camera) Render(w *World) *Canvas {
-	canvas := NewCanvas(c.Hsize, c.Vsize)
+	return c.render(w, false).Beauty
+}
+
+// RenderBuffers renders the beauty image along with the auxiliary buffers of
+// a RenderResult
+func (c *Camera) RenderBuffers(w *World) *RenderResult {
+	return c.render(w, true)
+}
+
+func (c *Camera) render(w *World, withAOVs bool) *RenderResult {
+	result := &RenderResult{Beauty: NewCanvas(c.Hsize, c.Vsize)}
+
+	var ids *sceneIDs
+
+	if withAOVs {
+		result = NewRenderResult(c.Hsize, c.Vsize)
+		ids = newSceneIDs(w)
+	}
+
+	if c.Transparent {
+		result.Beauty.Alpha = make([]float64, c.Hsize*c.Vsize)
+	}
 
 	var linesRendered int
 	start := time.Now()
@@ -78,9 +127,19 @@ func (c *Camera) Render(w *World) *Canvas {
 
 	for y := 0; y < c.Vsize; y++ {
 		for x := 0; x < c.Hsize; x++ {
-			color := c.getColorForPixels(float64(x), float64(y), w)
+			color, variance, alpha := c.samplePixel(float64(x), float64(y), w)
 
-			canvas.SetPixel(x, y, color)
+			if c.Transparent {
+				result.Beauty.SetAlpha(x, y, alpha)
+			}
+
+			if withAOVs {
+				aov := c.getAOVForPixel(float64(x), float64(y), w, ids)
+				aov.Variance = variance
+				result.SetPixel(x, y, color, aov)
+			} else {
+				result.Beauty.SetPixel(x, y, color)
+			}
 		}
 
 		linesRendered++
@@ -108,56 +167,205 @@ func (c *Camera) Render(w *World) *Canvas {
 
 	}
 
-	return canvas
+	return result
 }
 
 func (c *Camera) getColorForPixels(x, y float64, w *World) Color {
-	var outColor Color
+	color, _, _ := c.samplePixel(x, y, w)
+
+	return color
+}
+
+// samplePixel returns the color of a pixel together with the variance of
+// that estimate, which is zero for a single sample, and the fraction of
+// samples that hit something when the camera is transparent
+func (c *Camera) samplePixel(x, y float64, w *World) (Color, Color, float64) {
+	var outColor, variance Color
+	alpha := 1.0
+
+	if c.Samples <= 1 {
+		// A single sample goes through the pixel center so previews are stable
+		ray := c.RayForPixel(x+0.5, y+0.5)
+		outColor, alpha = c.sample(w, ray)
+	} else {
+		var covered float64
+		samples := make([]Color, c.Samples)
+
+		for i := 0; i < c.Samples; i++ {
+			x := x + w.Source.Float64()
+			y := y + w.Source.Float64()
+			ray := c.RayForPixel(x, y)
+			sample, coverage := c.sample(w, ray)
+
+			covered += coverage
+			samples[i] = sample
+			outColor = outColor.Add(sample)
+		}
+
+		outColor = outColor.MulFloat(1.0 / float64(c.Samples))
+		variance = varianceOfMean(samples, outColor)
+		alpha = covered / float64(c.Samples)
+
+		if c.MedianOfMeans > 1 {
+			// The group means are what the median picks from, so their spread
+			// around it is the variance of the returned color
+			means := groupMeans(samples, c.MedianOfMeans)
+			outColor = medianOfMeans(samples, c.MedianOfMeans)
+			variance = varianceOfMean(means, outColor)
+		}
+	}
+
+	return outColor, variance, alpha
+}
+
+// sample traces one camera ray. On a transparent camera the integrator
+// reports the coverage of the ray, and a ray that escapes to the background
+// is black, which premultiplies the pixel color by its alpha. Integrators that
+// cannot report coverage cover every pixel.
+func (c *Camera) sample(w *World, ray Ray) (Color, float64) {
+	if ci, ok := c.Integrator.(CoverageIntegrator); ok && c.Transparent {
+		return ci.ColorAndCoverageAt(w, &ray, c.Depth)
+	}
 
-	for i := 0; i < c.Samples; i++ {
-		x := x + w.Source.Float64()
-		y := y + w.Source.Float64()
-		ray := c.RayForPixel(x, y)
-		outColor = outColor.Add(w.ColorAt(&ray, c.Depth))
+	return c.Integrator.ColorAt(w, &ray, c.Depth), 1
+}
+
+// varianceOfMean estimates the variance of an average of values from their
+// spread around center
+func varianceOfMean(values []Color, center Color) Color {
+	var sumSquares Color
+
+	for _, v := range values {
+		d := v.Sub(center)
+		sumSquares = sumSquares.Add(d.Mul(d))
 	}
 
-	if c.GammaCorrection {
-		scale := 1.0 / float64(c.Samples)
-		outColor = NewColor(math.Sqrt(outColor.R*scale), math.Sqrt(outColor.G*scale), math.Sqrt(outColor.B*scale))
+	n := float64(len(values))
+
+	return sumSquares.MulFloat(1.0 / (n * (n - 1)))
+}
+
+// groupMeans splits samples into at most groups groups and averages each
+func groupMeans(samples []Color, groups int) []Color {
+	if groups > len(samples) {
+		groups = len(samples)
 	}
 
-	return outColor
+	means := make([]Color, groups)
+
+	for g := 0; g < groups; g++ {
+		start := g * len(samples) / groups
+		end := (g + 1) * len(samples) / groups
+
+		var sum Color
+		for _, s := range samples[start:end] {
+			sum = sum.Add(s)
+		}
+
+		means[g] = sum.MulFloat(1.0 / float64(end-start))
+	}
+
+	return means
+}
+
+// medianOfMeans splits samples into groups, averages each group and returns
+// the group mean with the median luminance
+func medianOfMeans(samples []Color, groups int) Color {
+	means := groupMeans(samples, groups)
+	groups = len(means)
+
+	sort.Slice(means, func(i, j int) bool {
+		return Luminance(means[i]) < Luminance(means[j])
+	})
+
+	if groups%2 == 1 {
+		return means[groups/2]
+	}
+
+	return means[groups/2-1].Add(means[groups/2]).MulFloat(0.5)
+}
+
+// RenderAmbientOcclusion renders an ambient occlusion buffer with the same
+// view and sampling as the camera, independent of its integrator
+func (c *Camera) RenderAmbientOcclusion(w *World, ao *AmbientOcclusion) *Canvas {
+	aoCamera := *c
+	aoCamera.Integrator = ao
+
+	return aoCamera.Render(w)
+}
+
+func (c *Camera) getAOVForPixel(x, y float64, w *World, ids *sceneIDs) AOVSample {
+	ray := c.RayForPixel(x+0.5, y+0.5)
+
+	return aovSample(w, ray, ids)
 }
 
 type response struct {
-	Y    int
-	line []Color
+	Y     int
+	line  []Color
+	alpha []float64
+	aovs  []AOVSample
 }
 
 type job struct {
 	Y      int
 	Camera *Camera
 	World  *World
+	AOVs   bool
 }
 
 func worker(jobChan chan job, responseChan chan response, wg *sync.WaitGroup) {
 	for job := range jobChan {
 		y := job.Y
 		line := make([]Color, 0, job.Camera.Hsize)
+		alphas := make([]float64, 0, job.Camera.Hsize)
+
+		var aovs []AOVSample
+		var ids *sceneIDs
+
+		if job.AOVs {
+			aovs = make([]AOVSample, 0, job.Camera.Hsize)
+			ids = newSceneIDs(job.World)
+		}
 
 		for x := 0; x < job.Camera.Hsize; x++ {
-			color := job.Camera.getColorForPixels(float64(x), float64(y), job.World)
+			color, variance, alpha := job.Camera.samplePixel(float64(x), float64(y), job.World)
 
 			line = append(line, color)
+			alphas = append(alphas, alpha)
+
+			if job.AOVs {
+				aov := job.Camera.getAOVForPixel(float64(x), float64(y), job.World, ids)
+				aov.Variance = variance
+				aovs = append(aovs, aov)
+			}
 		}
-		responseChan <- response{y, line}
+		responseChan <- response{y, line, alphas, aovs}
 
 		wg.Done()
 	}
 }
 
 func (c *Camera) RenderMultiThreaded(generator func() (*World, *Matrix), cores int) *Canvas {
-	canvas := NewCanvas(c.Hsize, c.Vsize)
+	return c.renderMultiThreaded(generator, cores, false).Beauty
+}
+
+// RenderBuffersMultiThreaded is RenderMultiThreaded that also fills the
+// auxiliary buffers of a RenderResult
+func (c *Camera) RenderBuffersMultiThreaded(generator func() (*World, *Matrix), cores int) *RenderResult {
+	return c.renderMultiThreaded(generator, cores, true)
+}
+
+func (c *Camera) renderMultiThreaded(generator func() (*World, *Matrix), cores int, withAOVs bool) *RenderResult {
+	result := &RenderResult{Beauty: NewCanvas(c.Hsize, c.Vsize)}
+
+	if withAOVs {
+		result = NewRenderResult(c.Hsize, c.Vsize)
+	}
+
+	if c.Transparent {
+		result.Beauty.Alpha = make([]float64, c.Hsize*c.Vsize)
+	}
 
 	jobChan := make(chan job)
 	responseChan := make(chan response)
@@ -176,7 +384,15 @@ func (c *Camera) RenderMultiThreaded(generator func() (*World, *Matrix), cores i
 
 		for response := range responseChan {
 			for x := 0; x < c.Hsize; x++ {
-				canvas.SetPixel(x, response.Y, response.line[x])
+				if c.Transparent {
+					result.Beauty.SetAlpha(x, response.Y, response.alpha[x])
+				}
+
+				if withAOVs {
+					result.SetPixel(x, response.Y, response.line[x], response.aovs[x])
+				} else {
+					result.Beauty.SetPixel(x, response.Y, response.line[x])
+				}
 			}
 
 			linesRendered++
@@ -214,11 +430,11 @@ func (c *Camera) RenderMultiThreaded(generator func() (*World, *Matrix), cores i
 		for y := 0; y < c.Vsize; y++ {
 			w, _ := generator()
 
-			jobChan <- job{y, c, w}
+			jobChan <- job{y, c, w, withAOVs}
 		}
 	}()
 
 	wg.Wait()
 
-	return canvas
+	return result
 }
@@ -2,6 +2,7 @@ package raytracer
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -82,7 +83,8 @@ func TestRayThroughCanvas(t *testing.T) {
 
 func TestRenderWorldWithCamera(t *testing.T) {
 	w := NewDefaultWorld()
-	c := NewCamera(11, 11, math.Pi/2)
+	c := NewCamera(11, 11, math.Pi/2).SetIntegrator(NewWhitted())
+	c.Samples = 1
 
 	from := NewPoint(0, 0, -5)
 	to := NewPoint(0, 0, 0)
@@ -98,3 +100,219 @@ func TestRenderWorldWithCamera(t *testing.T) {
 		t.Errorf("Got %v, want %v", got, want)
 	}
 }
+
+func TestRenderAveragesSamples(t *testing.T) {
+	w := NewWorld()
+	s := NewSphere()
+	s.SetTransform(NewScaling(10, 10, 10))
+	s.SetNewMaterial(NewEmissive(NewColor(0.5, 0.5, 0.5)))
+	w.AddObject(s)
+
+	c := NewCamera(3, 3, math.Pi/2)
+	c.Samples = 4
+
+	canvas := c.Render(w)
+	got := canvas.GetPixel(1, 1)
+	want := NewColor(0.5, 0.5, 0.5)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestRenderTransparent(t *testing.T) {
+	w := NewDefaultWorld()
+	w.Source = rand.New(rand.NewSource(1))
+	w.SetBackground(NewConstantBackground(NewColor(0, 0, 1)), false)
+
+	c := NewCamera(11, 11, math.Pi/2).SetIntegrator(NewWhitted()).SetTransparent(true)
+	c.SetTransform(ViewTransform(NewPoint(0, 0, -5), NewPoint(0, 0, 0), NewVec(0, 1, 0)))
+
+	testCases := []struct {
+		desc    string
+		samples int
+	}{
+		{"Single sample", 1},
+		{"Many samples", 16},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c.Samples = tC.samples
+			canvas := c.Render(w)
+
+			if got := canvas.GetAlpha(5, 5); got != 1 {
+				t.Errorf("Got alpha %v at the center, want 1", got)
+			}
+
+			if got := canvas.GetAlpha(0, 0); got != 0 {
+				t.Errorf("Got alpha %v in the corner, want 0", got)
+			}
+
+			if got := canvas.GetPixel(0, 0); got != colorBlack {
+				t.Errorf("Got %v in the corner, want black", got)
+			}
+		})
+	}
+
+	if canvas := c.SetTransparent(false).Render(w); canvas.Alpha != nil {
+		t.Errorf("Got an alpha channel, want none without transparency")
+	}
+}
+
+type noiseIntegrator struct{}
+
+func (noiseIntegrator) ColorAt(w *World, r *Ray, remaining int) Color {
+	if w.Source.Float64() < 0.5 {
+		return NewColor(0, 0, 0)
+	}
+
+	return NewColor(1, 1, 1)
+}
+
+func TestRenderBuffersVariance(t *testing.T) {
+	w := NewDefaultWorld()
+	w.Source = rand.New(rand.NewSource(1))
+
+	testCases := []struct {
+		desc       string
+		integrator Integrator
+		samples    int
+		want       float64
+	}{
+		{desc: "Single sample", integrator: noiseIntegrator{}, samples: 1, want: 0},
+		{desc: "Constant", integrator: constantIntegrator{NewColor(0.5, 0.5, 0.5)}, samples: 16, want: 0},
+		{desc: "Coin flip", integrator: noiseIntegrator{}, samples: 400, want: 0.25 / 400},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := NewCamera(2, 2, math.Pi/2).SetIntegrator(tC.integrator)
+			c.Samples = tC.samples
+
+			rr := c.RenderBuffers(w)
+
+			for _, p := range rr.Variance.Pixels {
+				if math.Abs(p.R-tC.want) > tC.want*0.2+1e-9 {
+					t.Errorf("Got %v, want %v", p.R, tC.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMedianOfMeans(t *testing.T) {
+	samples := []Color{
+		NewColor(1, 1, 1), NewColor(1, 1, 1),
+		NewColor(1000, 1000, 1000), NewColor(1, 1, 1),
+		NewColor(0.5, 0.5, 0.5), NewColor(0.5, 0.5, 0.5),
+	}
+
+	testCases := []struct {
+		desc   string
+		groups int
+		want   Color
+	}{
+		{desc: "Odd groups", groups: 3, want: NewColor(1, 1, 1)},
+		{desc: "Even groups", groups: 2, want: NewColor(167.333333, 167.333333, 167.333333)},
+		{desc: "One sample per group", groups: 6, want: NewColor(1, 1, 1)},
+		{desc: "More groups than samples", groups: 10, want: NewColor(1, 1, 1)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := medianOfMeans(samples, tC.groups)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestVarianceOfMean(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		values []Color
+		center Color
+		want   Color
+	}{
+		{
+			desc:   "Around the mean",
+			values: []Color{NewColor(0, 0, 0), NewColor(1, 1, 1)},
+			center: NewColor(0.5, 0.5, 0.5),
+			want:   NewColor(0.25, 0.25, 0.25),
+		},
+		{
+			desc:   "Around a median of means",
+			values: groupMeans([]Color{NewColor(0, 0, 0), NewColor(0, 0, 0), NewColor(1, 1, 1), NewColor(3, 3, 3)}, 2),
+			center: NewColor(1, 1, 1),
+			want:   NewColor(1, 1, 1),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := varianceOfMean(tC.values, tC.center)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestRenderTransparentCoverage(t *testing.T) {
+	thinMedium := NewSphere()
+	thinMedium.SetTransform(NewScaling(100, 100, 100))
+	thinMedium.SetNewMaterial(NewMedium(0, NewColor(1, 1, 1)))
+
+	testCases := []struct {
+		desc       string
+		world      func() *World
+		integrator Integrator
+		want       float64
+	}{
+		{
+			desc:       "Empty world",
+			world:      NewWorld,
+			integrator: NewPathTracer(),
+			want:       0,
+		},
+		{
+			desc: "Dense fog",
+			world: func() *World {
+				return NewWorld().SetFog(NewMedium(10, NewColor(0, 0, 0)), fogBox(100))
+			},
+			integrator: NewPathTracer(),
+			want:       1,
+		},
+		{
+			desc: "Medium crossed without scattering",
+			world: func() *World {
+				w := NewWorld()
+				w.AddObject(thinMedium)
+
+				return w
+			},
+			integrator: NewPathTracer(),
+			want:       0,
+		},
+		{
+			desc:       "Integrator without coverage",
+			world:      NewWorld,
+			integrator: constantIntegrator{NewColor(0.5, 0.5, 0.5)},
+			want:       1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := NewCamera(2, 2, math.Pi/2).SetIntegrator(tC.integrator).SetTransparent(true)
+			c.Samples = 4
+
+			canvas := c.Render(tC.world())
+
+			for i, got := range canvas.Alpha {
+				if got != tC.want {
+					t.Errorf("Got alpha %v at %d, want %v", got, i, tC.want)
+				}
+			}
+		})
+	}
+}
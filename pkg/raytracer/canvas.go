@@ -1,9 +1,14 @@
 package raytracer
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
 	"image"
+	"image/draw"
+	_ "image/jpeg"
 	"image/png"
+	"io"
 	"math"
 	"os"
 )
@@ -12,16 +17,136 @@ type Canvas struct {
 	Width  int
 	Height int
 	Pixels []Color
+	// Alpha is the coverage of every pixel, or nil when the canvas is
+	// opaque. Colors are premultiplied by it.
+	Alpha []float64
 }
 
 func NewCanvas(width, height int) *Canvas {
 	pixels := make([]Color, width*height)
 
 	return &Canvas{
-		width, height, pixels,
+		Width:  width,
+		Height: height,
+		Pixels: pixels,
 	}
 }
 
+// maxImagePixels bounds the size of images read from files so a corrupt
+// header cannot make a reader allocate an enormous canvas
+const maxImagePixels = 1 << 26
+
+// imagePixels returns the number of pixels in an image of the given size read
+// from a file, which must be positive and at most maxImagePixels
+func imagePixels(format string, width, height int) (int, error) {
+	if width <= 0 || height <= 0 {
+		return 0, fmt.Errorf("invalid %s size %dx%d", format, width, height)
+	}
+
+	if width > maxImagePixels/height {
+		return 0, fmt.Errorf("%s image %dx%d is too large", format, width, height)
+	}
+
+	return width * height, nil
+}
+
+// NewCanvasFromImage copies an sRGB encoded image into a linear canvas
+func NewCanvasFromImage(img image.Image) *Canvas {
+	return NewCanvasFromImageWithTransfer(img, SRGB)
+}
+
+// NewCanvasFromImageWithTransfer copies an image into a canvas, decoding its
+// values with a transfer function. Use Linear for data like normal maps.
+// Images with transparent pixels give the canvas an alpha channel.
+func NewCanvasFromImageWithTransfer(img image.Image, transfer TransferFunction) *Canvas {
+	bounds := img.Bounds()
+	canvas := NewCanvas(bounds.Dx(), bounds.Dy())
+
+	for y := 0; y < canvas.Height; y++ {
+		for x := 0; x < canvas.Width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+
+			if a == 0 {
+				canvas.SetAlpha(x, y, 0)
+				continue
+			}
+
+			// Values are premultiplied, but the transfer function applies to
+			// the straight color
+			alpha := float64(a) / 0xFFFF
+			decode := func(v uint32) float64 {
+				return transfer.Decode(float64(v)/float64(a)) * alpha
+			}
+
+			canvas.SetPixel(x, y, NewColor(decode(r), decode(g), decode(b)))
+
+			if a != 0xFFFF {
+				canvas.SetAlpha(x, y, alpha)
+			}
+		}
+	}
+
+	return canvas
+}
+
+// LoadCanvas reads an sRGB PNG, JPEG or PPM file, or a Radiance HDR, PFM or
+// OpenEXR file into a linear canvas
+func LoadCanvas(filename string) (*Canvas, error) {
+	return LoadCanvasWithTransfer(filename, SRGB)
+}
+
+// LoadCanvasWithTransfer reads a PNG, JPEG or PPM file into a canvas,
+// decoding its values with a transfer function. Radiance HDR, PFM and OpenEXR
+// files are already linear and are read as they are.
+func LoadCanvasWithTransfer(filename string, transfer TransferFunction) (*Canvas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	if magic, err := br.Peek(len(hdrMagic)); err == nil && string(magic) == hdrMagic {
+		return ReadHDR(br)
+	}
+
+	if magic, err := br.Peek(4); err == nil && binary.LittleEndian.Uint32(magic) == exrMagic {
+		return loadEXRCanvas(br)
+	}
+
+	if magic, err := br.Peek(2); err == nil {
+		switch string(magic) {
+		case "P3", "P6":
+			return ReadPPMWithTransfer(br, transfer)
+		case "PF", "Pf":
+			return ReadPFM(br)
+		}
+	}
+
+	img, _, err := image.Decode(br)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCanvasFromImageWithTransfer(img, transfer), nil
+}
+
+// loadEXRCanvas reads the unnamed layer of an OpenEXR file, or its first
+// layer when there is none
+func loadEXRCanvas(r io.Reader) (*Canvas, error) {
+	img, err := ReadEXR(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if layer := img.Layer(""); layer != nil {
+		return layer.Canvas, nil
+	}
+
+	return img.Layers[0].Canvas, nil
+}
+
 func (c *Canvas) GetPixel(x, y int) Color {
 	return c.Pixels[y*c.Width+x]
 }
@@ -30,94 +155,129 @@ func (c *Canvas) SetPixel(x, y int, color Color) {
 	c.Pixels[y*c.Width+x] = color
 }
 
-func getColorValue(c float64) int {
-	return int(math.Min(math.Max(math.Round(c*255), 0), 255))
-}
+// GetAlpha returns the coverage of a pixel, which is 1 on opaque canvases
+func (c *Canvas) GetAlpha(x, y int) float64 {
+	if c.Alpha == nil {
+		return 1
+	}
 
-func (c *Canvas) GetPPMString() string {
-	data := "P3\n"
-	data += fmt.Sprintf("%d %d\n", c.Width, c.Height)
-	data += "255\n"
+	return c.Alpha[y*c.Width+x]
+}
 
-	for y := 0; y < c.Height; y += 1 {
-		pixelValues := make([]string, 0)
+// SetAlpha sets the coverage of a pixel. Opaque canvases get an alpha
+// channel with every other pixel fully covered.
+func (c *Canvas) SetAlpha(x, y int, alpha float64) {
+	if c.Alpha == nil {
+		c.Alpha = make([]float64, c.Width*c.Height)
 
-		for x := 0; x < c.Width; x += 1 {
-			pixel := c.GetPixel(x, y)
-			r := getColorValue(pixel.R)
-			g := getColorValue(pixel.G)
-			b := getColorValue(pixel.B)
-
-			pixelValues = append(pixelValues, fmt.Sprint(r))
-			pixelValues = append(pixelValues, fmt.Sprint(g))
-			pixelValues = append(pixelValues, fmt.Sprint(b))
+		for i := range c.Alpha {
+			c.Alpha[i] = 1
 		}
+	}
 
-		line := ""
+	c.Alpha[y*c.Width+x] = alpha
+}
 
-		for _, value := range pixelValues {
-			if len(line)+len(value) >= 70 {
-				data += line + "\n"
-				line = ""
-			}
+// Normalized returns a copy of the canvas with all channels linearly mapped
+// from the smallest to the largest finite value in the canvas into [0, 1]
+func (c *Canvas) Normalized() *Canvas {
+	min, max := math.Inf(1), math.Inf(-1)
 
-			if line != "" {
-				line += " "
+	for _, p := range c.Pixels {
+		for _, v := range []float64{p.R, p.G, p.B} {
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				continue
 			}
 
-			line += value
+			min = math.Min(min, v)
+			max = math.Max(max, v)
 		}
+	}
 
-		data += line + "\n"
+	scale := 1.0
+	if max > min {
+		scale = 1 / (max - min)
 	}
 
-	return data
+	return c.Remap(func(p Color) Color {
+		return NewColor((p.R-min)*scale, (p.G-min)*scale, (p.B-min)*scale)
+	})
 }
 
-func (c *Canvas) SavePPM(filename string) {
-	f, err := os.Create(filename)
-	defer f.Close()
-	if err != nil {
-		fmt.Println(err)
-		return
+// Remap returns a copy of the canvas with f applied to every pixel
+func (c *Canvas) Remap(f func(Color) Color) *Canvas {
+	out := NewCanvas(c.Width, c.Height)
+
+	for i, p := range c.Pixels {
+		out.Pixels[i] = f(p)
 	}
 
-	data := c.GetPPMString()
+	if c.Alpha != nil {
+		out.Alpha = append([]float64(nil), c.Alpha...)
+	}
+
+	return out
+}
 
-	bytes, err := f.Write([]byte(data))
+func getColorValue(c float64) int {
+	return int(math.Min(math.Max(math.Round(c*255), 0), 255))
+}
+
+// saveCanvas creates a file and fills it with a buffered writer
+func saveCanvas(filename string, write func(io.Writer) error) error {
+	f, err := os.Create(filename)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
-	fmt.Println(bytes, "bytes written successfully")
+	w := bufio.NewWriter(f)
 
-	err = f.Close()
-	if err != nil {
-		fmt.Println(err)
-		return
+	if err := write(w); err != nil {
+		f.Close()
+		return err
 	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (c *Canvas) SavePNG(filename string) error {
+	return c.SavePNGWithOutput(filename, NewOutput())
 }
 
-func (c *Canvas) SavePNG(filename string) {
-	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+// SavePNGWithOutput saves the canvas exposed and tone mapped by output
+func (c *Canvas) SavePNGWithOutput(filename string, output *Output) error {
+	return saveCanvas(filename, func(w io.Writer) error {
+		return c.WritePNG(w, output)
+	})
+}
+
+// WritePNG encodes the canvas as a PNG with the bit depth of output.
+// Transparent canvases keep their alpha, with colors no longer
+// premultiplied as PNG expects.
+func (c *Canvas) WritePNG(w io.Writer, output *Output) error {
+	var img draw.Image
+
+	if output.BitDepth == 16 {
+		img = image.NewNRGBA64(image.Rect(0, 0, c.Width, c.Height))
+	} else {
+		img = image.NewNRGBA(image.Rect(0, 0, c.Width, c.Height))
+	}
 
 	for y := 0; y < c.Height; y += 1 {
 		for x := 0; x < c.Width; x += 1 {
-			pixel := c.GetPixel(x, y)
-			color := pixel.GetRGBA()
-
-			img.Set(x, y, color)
+			img.Set(x, y, output.Pixel(c.GetPixel(x, y), c.GetAlpha(x, y)))
 		}
 	}
 
-	f, err := os.Create(filename)
-	defer f.Close()
-	if err != nil {
-		fmt.Println(err)
-		f.Close()
-		return
-	}
+	return png.Encode(w, img)
+}
 
-	png.Encode(f, img)
+// ToneMapped returns a copy of the canvas exposed and tone mapped by output
+func (c *Canvas) ToneMapped(output *Output) *Canvas {
+	return c.Remap(output.Apply)
 }
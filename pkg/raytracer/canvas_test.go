@@ -1,6 +1,10 @@
 package raytracer
 
 import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
 	"strings"
 	"testing"
 )
@@ -56,7 +60,7 @@ func TestSavePPM(t *testing.T) {
 	canvas.SetPixel(2, 1, c2)
 	canvas.SetPixel(4, 2, c3)
 
-	ppm := canvas.GetPPMString()
+	ppm := canvas.GetPPMStringWithOutput(NewOutput().SetTransfer(Linear))
 	lines := strings.Split(ppm, "\n")
 
 	tests := []struct {
@@ -93,7 +97,7 @@ func TestSavePPM2(t *testing.T) {
 		}
 	}
 
-	ppm := canvas.GetPPMString()
+	ppm := canvas.GetPPMStringWithOutput(NewOutput().SetTransfer(Linear))
 	lines := strings.Split(ppm, "\n")
 
 	tests := []struct {
@@ -118,3 +122,94 @@ func TestSavePPM2(t *testing.T) {
 		})
 	}
 }
+
+func TestCanvasAlpha(t *testing.T) {
+	c := NewCanvas(2, 1)
+
+	if got := c.GetAlpha(0, 0); got != 1 {
+		t.Errorf("Got %v, want 1 on an opaque canvas", got)
+	}
+
+	c.SetAlpha(1, 0, 0.25)
+
+	testCases := []struct {
+		desc string
+		x    int
+		want float64
+	}{
+		{"Untouched pixel stays covered", 0, 1},
+		{"Set pixel", 1, 0.25},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := c.GetAlpha(tC.x, 0); got != tC.want {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+
+	if got := c.Remap(func(c Color) Color { return c }).GetAlpha(1, 0); got != 0.25 {
+		t.Errorf("Got %v after Remap, want 0.25", got)
+	}
+}
+
+func TestWritePNG16(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.SetPixel(0, 0, NewColor(0.001, 0.5, 1))
+
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf, NewOutput().SetTransfer(Linear).SetBitDepth(16)); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := img.(*image.NRGBA64); !ok {
+		if _, ok := img.(*image.RGBA64); !ok {
+			t.Fatalf("Got %T, want a 16-bit image", img)
+		}
+	}
+
+	// 0.001 is lost to 8 bits but not to 16
+	r, g, b, a := img.At(0, 0).RGBA()
+	want := [4]uint32{66, 32768, 0xFFFF, 0xFFFF}
+
+	if got := [4]uint32{r, g, b, a}; got != want {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPNGAlphaRoundTrip(t *testing.T) {
+	c := NewCanvas(3, 1)
+	c.SetPixel(0, 0, NewColor(0.5, 0.25, 0))
+	c.SetPixel(1, 0, NewColor(0.25, 0.125, 0))
+	c.SetAlpha(1, 0, 0.5)
+	c.SetAlpha(2, 0, 0)
+
+	for _, bits := range []int{8, 16} {
+		var buf bytes.Buffer
+		if err := c.WritePNG(&buf, NewOutput().SetBitDepth(bits)); err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := NewCanvasFromImage(img)
+
+		for x := 0; x < 3; x++ {
+			if a, want := got.GetAlpha(x, 0), c.GetAlpha(x, 0); math.Abs(a-want) > 1.0/255 {
+				t.Errorf("Got alpha %v at %d with %d bits, want %v", a, x, bits, want)
+			}
+
+			if p, want := got.GetPixel(x, 0), c.GetPixel(x, 0); !colorsClose(p, want, 0.02) {
+				t.Errorf("Got %v at %d with %d bits, want %v", p, x, bits, want)
+			}
+		}
+	}
+}
@@ -68,3 +68,8 @@ func (c Color) GetRGBA() color.Color {
 
 	return color.RGBA{r, g, b, 0xFF}
 }
+
+// Luminance returns the relative luminance of a linear color
+func Luminance(c Color) float64 {
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B
+}
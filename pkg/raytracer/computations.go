@@ -16,53 +16,86 @@ type Computations struct {
 	UnderPoint Point
 	N1         float64
 	N2         float64
+	// Enclosing is the material of the innermost object the ray travels
+	// through to reach the hit, nil when it is outside every object
+	Enclosing Scatters
 }
 
 func PrepareComputationsWithHit(i Intersection, r Ray, xs []Intersection) *Computations {
 	comps := PrepareComputations(i, r)
 
-	// var n1, n2 float64 = 1.0, 1.0
-
-	// containers := make([]*Intersectable, 0)
-	// for _, item := range xs {
-	// 	if i == item {
-	// 		if len(containers) == 0 {
-	// 			n1 = 1.0
-	// 		} else {
-	// 			n1 = (*containers[len(containers)-1]).GetMaterial().RefractiveIndex
-	// 		}
-	// 	}
-
-	// 	var itemIndex int = -1
-	// 	for index := 0; index < len(containers); index++ {
-	// 		if *containers[index] == *item.Object {
-	// 			itemIndex = index
-	// 		}
-	// 	}
-
-	// 	if itemIndex != -1 {
-	// 		containers = append(containers[:itemIndex], containers[itemIndex+1:]...)
-	// 	} else {
-	// 		containers = append(containers, item.Object)
-	// 	}
-	// 	if i == item {
-	// 		if len(containers) == 0 {
-	// 			n2 = 1.0
-	// 		} else {
-	// 			n2 = (*containers[len(containers)-1]).GetMaterial().RefractiveIndex
-	// 		}
-
-	// 		break
-	// 	}
-
-	// }
-
-	// comps.N1 = n1
-	// comps.N2 = n2
+	var n1, n2 float64 = 1.0, 1.0
+
+	containers := make([]container, 0)
+	for _, item := range xs {
+		if i == item {
+			if len(containers) == 0 {
+				n1 = 1.0
+			} else {
+				n1 = RefractiveIndex(containers[len(containers)-1].material)
+				comps.Enclosing = containers[len(containers)-1].material
+			}
+		}
+
+		solid := solidOf(*item.Object)
+
+		var itemIndex int = -1
+		for index := 0; index < len(containers); index++ {
+			if containers[index].solid == solid {
+				itemIndex = index
+			}
+		}
+
+		if itemIndex != -1 {
+			containers = append(containers[:itemIndex], containers[itemIndex+1:]...)
+		} else {
+			containers = append(containers, container{solid, (*item.Object).GetNewMaterial()})
+		}
+
+		if i == item {
+			if len(containers) == 0 {
+				n2 = 1.0
+			} else {
+				n2 = RefractiveIndex(containers[len(containers)-1].material)
+			}
+
+			break
+		}
+	}
+
+	comps.N1 = n1
+	comps.N2 = n2
 
 	return &comps
 }
 
+// container is a solid a ray is inside of and the material filling it
+type container struct {
+	solid    Intersectable
+	material Scatters
+}
+
+// solidOf returns the solid a ray enters or leaves when it hits an object.
+// Triangles are only surfaces, so a mesh built from them is entered and
+// exited as one solid: the nearest group marked Solid, or the group holding
+// the triangle when none is.
+func solidOf(object Intersectable) Intersectable {
+	switch object.(type) {
+	case *Triangle, *SmoothTriangle:
+		for parent := object.GetParent(); parent != nil; parent = parent.GetParent() {
+			if g, ok := parent.(*Group); ok && g.Solid {
+				return g
+			}
+		}
+
+		if parent := object.GetParent(); parent != nil {
+			return parent
+		}
+	}
+
+	return object
+}
+
 func PrepareComputations(i Intersection, r Ray) Computations {
 	p := r.Position(i.Time)
 
@@ -92,7 +125,7 @@ func PrepareComputations(i Intersection, r Ray) Computations {
 	}
 }
 
-func Schlick(comps Computations) float64 {
+func Schlick(comps *Computations) float64 {
 	cos := comps.Eyev.Dot(comps.Normalv)
 
 	if comps.N1 > comps.N2 {
@@ -139,3 +139,143 @@ func TestSchlickWithSmallAngleAndN2LargerThanN1(t *testing.T) {
 		t.Errorf("Got %v, want %v", reflectance, 0.48873)
 	}
 }
+
+func TestFindingN1AndN2ForAirBubbleInGlass(t *testing.T) {
+	glass := NewSphere().SetTransform(NewScaling(2, 2, 2))
+	glass.(*object).SetNewMaterial(NewDielectric(1.5))
+
+	bubble := NewSphere().SetTransform(NewScaling(0.5, 0.5, 0.5))
+	bubble.(*object).SetNewMaterial(NewDielectric(1.0))
+
+	r := NewRay(NewPoint(0, 0, -4), NewVec(0, 0, 1))
+
+	xs := []Intersection{
+		NewIntersection(2, glass),
+		NewIntersection(3.5, bubble),
+		NewIntersection(4.5, bubble),
+		NewIntersection(6, glass),
+	}
+
+	want := [][2]float64{{1.0, 1.5}, {1.5, 1.0}, {1.0, 1.5}, {1.5, 1.0}}
+
+	for index, w := range want {
+		comps := PrepareComputationsWithHit(xs[index], r, xs)
+
+		if comps.N1 != w[0] || comps.N2 != w[1] {
+			t.Errorf("Intersection %d, got n1 %v n2 %v, want n1 %v n2 %v", index, comps.N1, comps.N2, w[0], w[1])
+		}
+	}
+}
+
+func TestFindingN1AndN2ForTrianglesInOneMesh(t *testing.T) {
+	glass := NewDielectric(1.5)
+
+	front := NewTriangle(NewPoint(-1, -1, 0), NewPoint(1, -1, 0), NewPoint(0, 1, 0))
+	front.SetNewMaterial(glass)
+	back := NewTriangle(NewPoint(-1, -1, 1), NewPoint(1, -1, 1), NewPoint(0, 1, 1))
+	back.SetNewMaterial(glass)
+
+	// The triangles end up in different subgroups, as after dividing a mesh
+	left, right := NewGroup(), NewGroup()
+	left.AddChild(front)
+	right.AddChild(back)
+
+	mesh := NewGroup().SetSolid(true)
+	mesh.AddChild(left)
+	mesh.AddChild(right)
+
+	r := NewRay(NewPoint(0, 0, -4), NewVec(0, 0, 1))
+
+	xs := []Intersection{
+		NewIntersection(4, front),
+		NewIntersection(5, back),
+	}
+
+	comps := PrepareComputationsWithHit(xs[1], r, xs)
+
+	if comps.N1 != 1.5 || comps.N2 != 1.0 {
+		t.Errorf("Got n1 %v n2 %v, want n1 %v n2 %v", comps.N1, comps.N2, 1.5, 1.0)
+	}
+}
+
+func TestFindingN1AndN2ForMeshesInOneGroup(t *testing.T) {
+	mesh := func(material Scatters, nearZ, farZ float64) (*Group, *Triangle, *Triangle) {
+		near := NewTriangle(NewPoint(-1, -1, nearZ), NewPoint(1, -1, nearZ), NewPoint(0, 1, nearZ))
+		near.SetNewMaterial(material)
+		far := NewTriangle(NewPoint(-1, -1, farZ), NewPoint(1, -1, farZ), NewPoint(0, 1, farZ))
+		far.SetNewMaterial(material)
+
+		g := NewGroup().SetSolid(true)
+		g.AddChild(near)
+		g.AddChild(far)
+
+		return g, near, far
+	}
+
+	glass, glassNear, glassFar := mesh(NewDielectric(1.5), 0, 3)
+	water, waterNear, waterFar := mesh(NewDielectric(1.33), 1, 2)
+
+	scene := NewGroup()
+	scene.AddChild(glass)
+	scene.AddChild(water)
+
+	r := NewRay(NewPoint(0, 0, -4), NewVec(0, 0, 1))
+
+	xs := []Intersection{
+		NewIntersection(4, glassNear),
+		NewIntersection(5, waterNear),
+		NewIntersection(6, waterFar),
+		NewIntersection(7, glassFar),
+	}
+
+	testCases := []struct {
+		desc   string
+		index  int
+		n1, n2 float64
+	}{
+		{"Into glass", 0, 1.0, 1.5},
+		{"Into water", 1, 1.5, 1.33},
+		{"Out of water", 2, 1.33, 1.5},
+		{"Out of glass", 3, 1.5, 1.0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			comps := PrepareComputationsWithHit(xs[tC.index], r, xs)
+
+			if comps.N1 != tC.n1 || comps.N2 != tC.n2 {
+				t.Errorf("Got n1 %v n2 %v, want n1 %v n2 %v", comps.N1, comps.N2, tC.n1, tC.n2)
+			}
+		})
+	}
+}
+
+func TestFindingN1AndN2ForOverlappingSolidsSharingMaterial(t *testing.T) {
+	glass := NewDielectric(1.5)
+
+	a := NewSphere()
+	a.SetNewMaterial(glass)
+	a.SetTransform(NewTranslation(0, 0, -0.5))
+
+	b := NewSphere()
+	b.SetNewMaterial(glass)
+	b.SetTransform(NewTranslation(0, 0, 0.5))
+
+	r := NewRay(NewPoint(0, 0, -4), NewVec(0, 0, 1))
+
+	xs := []Intersection{
+		NewIntersection(2.5, a),
+		NewIntersection(3.5, b),
+		NewIntersection(4.5, a),
+		NewIntersection(5.5, b),
+	}
+
+	want := [][2]float64{{1.0, 1.5}, {1.5, 1.5}, {1.5, 1.5}, {1.5, 1.0}}
+
+	for index, w := range want {
+		comps := PrepareComputationsWithHit(xs[index], r, xs)
+
+		if comps.N1 != w[0] || comps.N2 != w[1] {
+			t.Errorf("Intersection %d, got n1 %v n2 %v, want n1 %v n2 %v", index, comps.N1, comps.N2, w[0], w[1])
+		}
+	}
+}
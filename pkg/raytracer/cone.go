@@ -106,6 +106,22 @@ func (co *Cone) LocalNormalAt(objectPoint Point, i Intersection) Vec {
 	return objectNormal
 }
 
+// LocalUVAt maps the cone like a cylinder, with caps as wide as the cone is
+// where they close it
+func (co *Cone) LocalUVAt(objectPoint Point) (u, v float64) {
+	if co.Closed {
+		for _, y := range []float64{co.Minimum, co.Maximum} {
+			radius := math.Abs(y)
+
+			if math.Abs(objectPoint.Y-y) <= 1e-5 && objectPoint.X*objectPoint.X+objectPoint.Z*objectPoint.Z < radius*radius {
+				return discUV(objectPoint, radius)
+			}
+		}
+	}
+
+	return azimuthU(objectPoint), sideV(objectPoint.Y, co.Minimum, co.Maximum)
+}
+
 func checkCap2(r Ray, t float64, radius float64) bool {
 	x := r.Origin.X + t*r.Direction.X
 	z := r.Origin.Z + t*r.Direction.Z
@@ -41,7 +41,7 @@ func NewCSG(operation Operation, left Intersectable, right Intersectable) *CSG {
 func (csg *CSG) Intersect(worldRay Ray) []Intersection {
 	objectRay := worldRay.Mul(csg.Transform.Inverse())
 
-	return csg.Intersect(objectRay)
+	return csg.LocalIntersect(objectRay)
 }
 
 func (csg *CSG) LocalIntersect(objectRay Ray) []Intersection {
@@ -19,11 +19,11 @@ func TestNewCSG(t *testing.T) {
 		t.Error("Invalid right")
 	}
 
-	if s.Parent != csg {
+	if s.GetParent() != csg {
 		t.Error("Invalid sphere parent")
 	}
 
-	if c.Parent != csg {
+	if c.GetParent() != csg {
 		t.Error("Invalid cube parent")
 	}
 }
@@ -67,6 +67,10 @@ func (c *Cube) LocalNormalAt(objectPoint Point, i Intersection) Vec {
 	return objectNormal
 }
 
+func (c *Cube) LocalUVAt(objectPoint Point) (u, v float64) {
+	return CubeMap(objectPoint)
+}
+
 func checkAxis(origin, direction float64) (float64, float64) {
 	tmin_numerator := -1 - origin
 	tmax_numerator := 1 - origin
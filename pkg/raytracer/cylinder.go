@@ -93,6 +93,18 @@ func (c *Cylinder) LocalNormalAt(objectPoint Point, i Intersection) Vec {
 	return objectNormal.Norm()
 }
 
+// LocalUVAt wraps u around the side with v running from Minimum to Maximum,
+// and maps each cap onto the whole texture
+func (c *Cylinder) LocalUVAt(objectPoint Point) (u, v float64) {
+	dist := objectPoint.X*objectPoint.X + objectPoint.Z*objectPoint.Z
+
+	if c.Closed && dist < 1 && (objectPoint.Y >= c.Maximum-1e-5 || objectPoint.Y <= c.Minimum+1e-5) {
+		return discUV(objectPoint, 1)
+	}
+
+	return azimuthU(objectPoint), sideV(objectPoint.Y, c.Minimum, c.Maximum)
+}
+
 func checkCap(r Ray, t float64) bool {
 	x := r.Origin.X + t*r.Direction.X
 	z := r.Origin.Z + t*r.Direction.Z
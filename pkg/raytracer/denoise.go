@@ -0,0 +1,251 @@
+package raytracer
+
+import (
+	"math"
+)
+
+// atrousKernel is the 1D B3 spline used by every à-trous pass
+var atrousKernel = [5]float64{1.0 / 16, 1.0 / 4, 3.0 / 8, 1.0 / 4, 1.0 / 16}
+
+// Denoiser filters a noisy render with the edge-avoiding à-trous wavelet
+// transform. Each pass blurs with a 5x5 kernel whose taps are twice as far
+// apart as in the pass before, weighted down across differences in normal,
+// depth and albedo and across color differences larger than the noise.
+type Denoiser struct {
+	Iterations int
+	// SigmaColor scales how many standard deviations of noise two pixels
+	// may differ by and still be blended
+	SigmaColor float64
+	// SigmaNormal is the exponent on the cosine between two normals
+	SigmaNormal float64
+	// SigmaDepth is the relative depth difference allowed per pixel of
+	// distance
+	SigmaDepth float64
+	// SigmaAlbedo is the albedo difference allowed
+	SigmaAlbedo float64
+}
+
+func NewDenoiser() *Denoiser {
+	return &Denoiser{
+		Iterations:  5,
+		SigmaColor:  4,
+		SigmaNormal: 128,
+		SigmaDepth:  0.05,
+		SigmaAlbedo: 0.1,
+	}
+}
+
+func (d *Denoiser) SetIterations(i int) *Denoiser {
+	d.Iterations = i
+
+	return d
+}
+
+// denoiseImage is the working state of the filter for one render
+type denoiseImage struct {
+	width, height int
+	color         []Color
+	variance      []float64
+	normal        []Vec
+	depth         []float64
+	albedo        []Color
+}
+
+// Denoise returns a filtered copy of the beauty buffer. The render must have
+// been made with RenderBuffers so the feature buffers are filled in. Lighting
+// is filtered separately from albedo so textures stay sharp. The alpha of a
+// transparent render is copied unfiltered.
+func (d *Denoiser) Denoise(rr *RenderResult) *Canvas {
+	img := newDenoiseImage(rr)
+
+	for i := 0; i < d.Iterations; i++ {
+		d.pass(img, 1<<uint(i))
+	}
+
+	out := NewCanvas(img.width, img.height)
+
+	for i, c := range img.color {
+		out.Pixels[i] = modulate(c, img.albedo[i])
+	}
+
+	if rr.Beauty.Alpha != nil {
+		out.Alpha = append([]float64(nil), rr.Beauty.Alpha...)
+	}
+
+	return out
+}
+
+func newDenoiseImage(rr *RenderResult) *denoiseImage {
+	width, height := rr.Beauty.Width, rr.Beauty.Height
+	n := width * height
+
+	img := &denoiseImage{
+		width:    width,
+		height:   height,
+		color:    make([]Color, n),
+		variance: make([]float64, n),
+		normal:   make([]Vec, n),
+		depth:    make([]float64, n),
+		albedo:   make([]Color, n),
+	}
+
+	for i := 0; i < n; i++ {
+		albedo := rr.Albedo.Pixels[i]
+		normal := rr.Normal.Pixels[i]
+
+		img.albedo[i] = albedo
+		img.color[i] = demodulate(rr.Beauty.Pixels[i], albedo)
+		img.normal[i] = NewVec(normal.R, normal.G, normal.B)
+		img.depth[i] = rr.Depth.Pixels[i].R
+
+		// The variance of the lighting is the variance of the beauty
+		// divided by the squared albedo
+		variance := Luminance(rr.Variance.Pixels[i])
+		if a := Luminance(albedo); a > 1e-3 {
+			variance /= a * a
+		}
+		img.variance[i] = variance
+	}
+
+	return img
+}
+
+func (d *Denoiser) pass(img *denoiseImage, step int) {
+	color := make([]Color, len(img.color))
+	variance := make([]float64, len(img.variance))
+
+	// Edge stopping on color uses a slightly blurred variance, which is
+	// less noisy than the variance of a single pixel
+	blurred := img.blurredVariance()
+
+	for y := 0; y < img.height; y++ {
+		for x := 0; x < img.width; x++ {
+			p := y*img.width + x
+
+			colorScale := d.SigmaColor*math.Sqrt(blurred[p]) + 1e-6
+			luminance := Luminance(img.color[p])
+
+			var sumColor Color
+			var sumWeight, sumVariance float64
+
+			for ky := -2; ky <= 2; ky++ {
+				for kx := -2; kx <= 2; kx++ {
+					qx, qy := x+kx*step, y+ky*step
+
+					if qx < 0 || qy < 0 || qx >= img.width || qy >= img.height {
+						continue
+					}
+
+					q := qy*img.width + qx
+
+					w := atrousKernel[kx+2] * atrousKernel[ky+2]
+
+					if q != p {
+						distance := math.Sqrt(float64(kx*kx+ky*ky)) * float64(step)
+
+						w *= d.normalWeight(img.normal[p], img.normal[q])
+						w *= d.depthWeight(img.depth[p], img.depth[q], distance)
+						w *= d.albedoWeight(img.albedo[p], img.albedo[q])
+						w *= math.Exp(-math.Abs(luminance-Luminance(img.color[q])) / colorScale)
+					}
+
+					sumColor = sumColor.Add(img.color[q].MulFloat(w))
+					sumVariance += w * w * img.variance[q]
+					sumWeight += w
+				}
+			}
+
+			color[p] = sumColor.MulFloat(1 / sumWeight)
+			variance[p] = sumVariance / (sumWeight * sumWeight)
+		}
+	}
+
+	img.color = color
+	img.variance = variance
+}
+
+// blurredVariance filters the variance with a 3x3 Gaussian
+func (img *denoiseImage) blurredVariance() []float64 {
+	kernel := [3]float64{0.25, 0.5, 0.25}
+	out := make([]float64, len(img.variance))
+
+	for y := 0; y < img.height; y++ {
+		for x := 0; x < img.width; x++ {
+			var sum, weight float64
+
+			for ky := -1; ky <= 1; ky++ {
+				for kx := -1; kx <= 1; kx++ {
+					qx, qy := x+kx, y+ky
+
+					if qx < 0 || qy < 0 || qx >= img.width || qy >= img.height {
+						continue
+					}
+
+					w := kernel[kx+1] * kernel[ky+1]
+					sum += w * img.variance[qy*img.width+qx]
+					weight += w
+				}
+			}
+
+			out[y*img.width+x] = sum / weight
+		}
+	}
+
+	return out
+}
+
+// normalWeight keeps pixels that missed everything, and so have no normal,
+// apart from pixels that hit something
+func (d *Denoiser) normalWeight(a, b Vec) float64 {
+	aMissed, bMissed := a.LengthSquared() == 0, b.LengthSquared() == 0
+
+	if aMissed || bMissed {
+		if aMissed && bMissed {
+			return 1
+		}
+
+		return 0
+	}
+
+	return math.Pow(math.Max(0, a.Dot(b)), d.SigmaNormal)
+}
+
+func (d *Denoiser) depthWeight(a, b, distance float64) float64 {
+	scale := d.SigmaDepth*math.Max(a, 1e-3)*distance + 1e-6
+
+	return math.Exp(-math.Abs(a-b) / scale)
+}
+
+func (d *Denoiser) albedoWeight(a, b Color) float64 {
+	difference := a.Sub(b)
+	squared := difference.R*difference.R + difference.G*difference.G + difference.B*difference.B
+
+	return math.Exp(-squared / (d.SigmaAlbedo * d.SigmaAlbedo))
+}
+
+// demodulate divides the albedo out of a color, leaving the lighting
+func demodulate(c, albedo Color) Color {
+	return NewColor(safeDivide(c.R, albedo.R), safeDivide(c.G, albedo.G), safeDivide(c.B, albedo.B))
+}
+
+func modulate(c, albedo Color) Color {
+	return NewColor(safeMultiply(c.R, albedo.R), safeMultiply(c.G, albedo.G), safeMultiply(c.B, albedo.B))
+}
+
+// safeDivide leaves channels with almost no albedo as they are, so
+// demodulating and modulating them again is lossless
+func safeDivide(c, albedo float64) float64 {
+	if albedo < 1e-3 {
+		return c
+	}
+
+	return c / albedo
+}
+
+func safeMultiply(c, albedo float64) float64 {
+	if albedo < 1e-3 {
+		return c
+	}
+
+	return c * albedo
+}
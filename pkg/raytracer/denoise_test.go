@@ -0,0 +1,120 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// noisyRenderResult is a render of two flat regions meeting at a vertical
+// edge, with gaussian noise added to the beauty buffer
+func noisyRenderResult(width, height int, noise float64) (*RenderResult, *Canvas) {
+	source := rand.New(rand.NewSource(1))
+	rr := NewRenderResult(width, height)
+	clean := NewCanvas(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value, normal := 0.6, NewVec(0, 0, -1)
+			if x >= width/2 {
+				value, normal = 0.1, NewVec(1, 0, 0)
+			}
+
+			c := NewColor(value, value, value)
+			clean.SetPixel(x, y, c)
+
+			n := source.NormFloat64() * noise
+			beauty := c.Add(NewColor(n, n, n))
+			variance := noise * noise
+
+			rr.SetPixel(x, y, beauty, AOVSample{
+				Depth:    5,
+				Normal:   normal,
+				Albedo:   NewColor(1, 1, 1),
+				Variance: NewColor(variance, variance, variance),
+			})
+		}
+	}
+
+	return rr, clean
+}
+
+func canvasError(a, b *Canvas) float64 {
+	var sum float64
+
+	for i := range a.Pixels {
+		d := a.Pixels[i].R - b.Pixels[i].R
+		sum += d * d
+	}
+
+	return math.Sqrt(sum / float64(len(a.Pixels)))
+}
+
+func TestDenoiserReducesNoise(t *testing.T) {
+	rr, clean := noisyRenderResult(32, 32, 0.1)
+
+	denoised := NewDenoiser().Denoise(rr)
+
+	before := canvasError(rr.Beauty, clean)
+	after := canvasError(denoised, clean)
+
+	if after > before/3 {
+		t.Errorf("Got error %v after denoising, want well below %v", after, before)
+	}
+}
+
+func TestDenoiserKeepsEdges(t *testing.T) {
+	rr, _ := noisyRenderResult(32, 32, 0.1)
+
+	denoised := NewDenoiser().Denoise(rr)
+
+	for y := 0; y < 32; y++ {
+		left := denoised.GetPixel(15, y).R
+		right := denoised.GetPixel(16, y).R
+
+		if math.Abs(left-0.6) > 0.1 || math.Abs(right-0.1) > 0.1 {
+			t.Fatalf("Row %d, got %v and %v at the edge, want 0.6 and 0.1", y, left, right)
+		}
+	}
+}
+
+func TestDenoiserKeepsAlbedoDetail(t *testing.T) {
+	rr := NewRenderResult(8, 8)
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			albedo := NewColor(0.2, 0.2, 0.2)
+			if (x+y)%2 == 0 {
+				albedo = NewColor(0.8, 0.8, 0.8)
+			}
+
+			rr.SetPixel(x, y, albedo, AOVSample{Depth: 1, Normal: NewVec(0, 1, 0), Albedo: albedo})
+		}
+	}
+
+	denoised := NewDenoiser().Denoise(rr)
+
+	if got := canvasError(denoised, rr.Beauty); got > 1e-9 {
+		t.Errorf("Got error %v, want a checkerboard albedo to be untouched", got)
+	}
+}
+
+func TestDenoiserKeepsAlpha(t *testing.T) {
+	rr, _ := noisyRenderResult(8, 8, 0.1)
+
+	if denoised := NewDenoiser().Denoise(rr); denoised.Alpha != nil {
+		t.Errorf("Got an alpha channel, want none for an opaque render")
+	}
+
+	for i := range rr.Beauty.Pixels {
+		rr.Beauty.SetAlpha(i%8, i/8, float64(i%3)/2)
+	}
+
+	denoised := NewDenoiser().Denoise(rr)
+
+	for i, want := range rr.Beauty.Alpha {
+		if got := denoised.Alpha[i]; got != want {
+			t.Errorf("Got alpha %v at %d, want %v", got, i, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package raytracer
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Environment is a Background that integrators can sample directly as a
+// light, so it reports the density of its samples.
+type Environment interface {
+	Background
+	Sample(source *rand.Rand) (Vec, float64)
+	Pdf(direction Vec) float64
+}
+
+// EnvironmentMap lights the world from an equirectangular image at infinity.
+// The center of the image looks down -z, with +y at the top. Directions are
+// importance sampled by luminance so small bright areas like a sun converge.
+type EnvironmentMap struct {
+	Image     *Canvas
+	Transform *Matrix
+	Intensity float64
+
+	marginal    []float64
+	conditional [][]float64
+	total       float64
+}
+
+// NewEnvironmentMap panics when the image has no pixels to sample
+func NewEnvironmentMap(image *Canvas) *EnvironmentMap {
+	if image.Width <= 0 || image.Height <= 0 {
+		panic("Environment map image is empty")
+	}
+
+	em := &EnvironmentMap{
+		Image:     image,
+		Transform: NewIdentityMatrix(),
+		Intensity: 1,
+	}
+
+	em.buildDistribution()
+
+	return em
+}
+
+func LoadEnvironmentMap(filename string) (*EnvironmentMap, error) {
+	image, err := LoadCanvas(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if image.Width <= 0 || image.Height <= 0 {
+		return nil, errors.New("environment map image is empty")
+	}
+
+	return NewEnvironmentMap(image), nil
+}
+
+// SetTransform orients the environment, usually with a rotation
+func (em *EnvironmentMap) SetTransform(m *Matrix) *EnvironmentMap {
+	em.Transform = m
+
+	return em
+}
+
+func (em *EnvironmentMap) SetIntensity(i float64) *EnvironmentMap {
+	em.Intensity = i
+
+	return em
+}
+
+// buildDistribution sets up the 2D CDF over the pixels, weighted by
+// luminance and by the solid angle each row covers
+func (em *EnvironmentMap) buildDistribution() {
+	width, height := em.Image.Width, em.Image.Height
+
+	em.marginal = make([]float64, height)
+	em.conditional = make([][]float64, height)
+	em.total = 0
+
+	for y := 0; y < height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+		row := make([]float64, width)
+
+		var rowSum float64
+		for x := 0; x < width; x++ {
+			rowSum += Luminance(em.Image.GetPixel(x, y)) * sinTheta
+			row[x] = rowSum
+		}
+
+		em.conditional[y] = row
+		em.total += rowSum
+		em.marginal[y] = em.total
+	}
+}
+
+// ColorAt returns the radiance arriving from the given world direction
+func (em *EnvironmentMap) ColorAt(direction Vec) Color {
+	x, y := em.pixelFor(em.Transform.Inverse().MulVec(direction).Norm())
+
+	return em.Image.GetPixel(x, y).MulFloat(em.Intensity)
+}
+
+// Sample picks a world direction proportional to the luminance of the map
+// and returns it with its probability density per unit solid angle
+func (em *EnvironmentMap) Sample(source *rand.Rand) (Vec, float64) {
+	if em.total <= 0 {
+		return RandomInUnitSphere(source), 1 / (4 * math.Pi)
+	}
+
+	y := sort.SearchFloat64s(em.marginal, source.Float64()*em.total)
+	y = clampIndex(y, em.Image.Height)
+
+	row := em.conditional[y]
+	x := sort.SearchFloat64s(row, source.Float64()*row[len(row)-1])
+	x = clampIndex(x, em.Image.Width)
+
+	u := (float64(x) + source.Float64()) / float64(em.Image.Width)
+	v := (float64(y) + source.Float64()) / float64(em.Image.Height)
+
+	local := directionFromUV(u, v)
+	direction := em.Transform.MulVec(local).Norm()
+
+	return direction, em.pdf(x, y, v)
+}
+
+// Pdf returns the density Sample would choose the world direction with
+func (em *EnvironmentMap) Pdf(direction Vec) float64 {
+	if em.total <= 0 {
+		return 1 / (4 * math.Pi)
+	}
+
+	local := em.Transform.Inverse().MulVec(direction).Norm()
+	x, y := em.pixelFor(local)
+	v := math.Acos(math.Max(-1, math.Min(1, local.Y))) / math.Pi
+
+	return em.pdf(x, y, v)
+}
+
+func (em *EnvironmentMap) pdf(x, y int, v float64) float64 {
+	sinTheta := math.Sin(math.Pi * v)
+
+	if sinTheta <= 0 {
+		return 0
+	}
+
+	rowSinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(em.Image.Height))
+	weight := Luminance(em.Image.GetPixel(x, y)) * rowSinTheta
+	pixels := float64(em.Image.Width * em.Image.Height)
+
+	return weight / em.total * pixels / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+func (em *EnvironmentMap) pixelFor(local Vec) (int, int) {
+	u := 0.5 + math.Atan2(local.X, -local.Z)/(2*math.Pi)
+	v := math.Acos(math.Max(-1, math.Min(1, local.Y))) / math.Pi
+
+	x := clampIndex(int(u*float64(em.Image.Width)), em.Image.Width)
+	y := clampIndex(int(v*float64(em.Image.Height)), em.Image.Height)
+
+	return x, y
+}
+
+func directionFromUV(u, v float64) Vec {
+	phi := 2 * math.Pi * (u - 0.5)
+	theta := math.Pi * v
+
+	return NewVec(math.Sin(theta)*math.Sin(phi), math.Cos(theta), -math.Sin(theta)*math.Cos(phi))
+}
+
+func clampIndex(i, size int) int {
+	if i < 0 {
+		return 0
+	}
+
+	if i >= size {
+		return size - 1
+	}
+
+	return i
+}
@@ -0,0 +1,164 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func uniformCanvas(width, height int, c Color) *Canvas {
+	canvas := NewCanvas(width, height)
+
+	for i := range canvas.Pixels {
+		canvas.Pixels[i] = c
+	}
+
+	return canvas
+}
+
+func TestEnvironmentMapColorAt(t *testing.T) {
+	image := uniformCanvas(4, 2, NewColor(0.1, 0.1, 0.1))
+	image.SetPixel(2, 0, NewColor(1, 0, 0))
+
+	em := NewEnvironmentMap(image)
+
+	testCases := []struct {
+		desc string
+		em   *EnvironmentMap
+		dir  Vec
+		want Color
+	}{
+		{
+			desc: "Bright pixel",
+			em:   em,
+			dir:  NewVec(0.5, math.Sqrt(2)/2, -0.5),
+			want: NewColor(1, 0, 0),
+		},
+		{
+			desc: "Other pixel",
+			em:   em,
+			dir:  NewVec(-0.5, math.Sqrt(2)/2, -0.5),
+			want: NewColor(0.1, 0.1, 0.1),
+		},
+		{
+			desc: "Rotated",
+			em:   NewEnvironmentMap(image).SetTransform(NewRotationY(math.Pi / 2)),
+			dir:  NewVec(-0.5, math.Sqrt(2)/2, -0.5),
+			want: NewColor(1, 0, 0),
+		},
+		{
+			desc: "Scaled",
+			em:   NewEnvironmentMap(image).SetIntensity(2),
+			dir:  NewVec(0.5, math.Sqrt(2)/2, -0.5),
+			want: NewColor(2, 0, 0),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := tC.em.ColorAt(tC.dir)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentMapUniformPdf(t *testing.T) {
+	em := NewEnvironmentMap(uniformCanvas(64, 32, NewColor(1, 1, 1)))
+
+	// Direction through the center of pixel (20, 10)
+	got := em.Pdf(directionFromUV(20.5/64, 10.5/32))
+	want := 1 / (4 * math.Pi)
+
+	if !WithinTolerance(got, want, 1e-2) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestEnvironmentMapSampleFollowsLuminance(t *testing.T) {
+	image := uniformCanvas(8, 4, NewColor(0.01, 0.01, 0.01))
+	image.SetPixel(5, 1, NewColor(100, 100, 100))
+
+	em := NewEnvironmentMap(image).SetTransform(NewRotationY(0.3))
+	source := rand.New(rand.NewSource(1))
+
+	var bright int
+	samples := 1000
+
+	for i := 0; i < samples; i++ {
+		direction, pdf := em.Sample(source)
+
+		if !WithinTolerance(em.Pdf(direction), pdf, 1e-6) {
+			t.Fatalf("Pdf mismatch, got %v, want %v", em.Pdf(direction), pdf)
+		}
+
+		if em.ColorAt(direction).R == 100 {
+			bright++
+		}
+	}
+
+	if bright < samples*9/10 {
+		t.Errorf("Got %d samples in bright pixel, want at least %d", bright, samples*9/10)
+	}
+}
+
+func TestPathTracerLitByEnvironment(t *testing.T) {
+	w := NewWorld()
+	w.Background = NewEnvironmentMap(uniformCanvas(16, 8, NewColor(1, 1, 1)))
+	w.Source = rand.New(rand.NewSource(1))
+
+	floor := NewPlane()
+	floor.SetNewMaterial(NewDiffuse(NewColor(0.5, 0.5, 0.5)))
+	w.AddObject(floor)
+
+	r := NewRay(NewPoint(0, 1, -1), NewVec(0, -1, 1).Norm())
+
+	var sum Color
+	samples := 4000
+
+	for i := 0; i < samples; i++ {
+		sum = sum.Add(NewPathTracer().ColorAt(w, &r, 4))
+	}
+
+	got := sum.MulFloat(1 / float64(samples))
+
+	if math.Abs(got.R-0.5) > 0.03 {
+		t.Errorf("Got %v, want %v", got, NewColor(0.5, 0.5, 0.5))
+	}
+}
+
+func TestWorldMissColorUsesEnvironment(t *testing.T) {
+	w := NewWorld()
+	w.Background = NewEnvironmentMap(uniformCanvas(4, 2, NewColor(0.7, 0.7, 0.7)))
+
+	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
+
+	got := w.MissColor(&r)
+
+	if !got.Eq(NewColor(0.7, 0.7, 0.7)) {
+		t.Errorf("Got %v, want %v", got, NewColor(0.7, 0.7, 0.7))
+	}
+}
+
+func TestEnvironmentMapRejectsEmptyImages(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		width, height int
+	}{
+		{"No pixels", 0, 0},
+		{"No columns", 0, 4},
+		{"No rows", 4, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+
+			NewEnvironmentMap(NewCanvas(tC.width, tC.height))
+		})
+	}
+}
@@ -0,0 +1,837 @@
+package raytracer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// exrMagic starts every OpenEXR file
+const exrMagic = 20000630
+
+// exrLongNames is the version flag for names longer than 31 bytes
+const exrLongNames = 0x400
+
+type EXRPixelType int32
+
+const (
+	EXRHalf  EXRPixelType = 1
+	EXRFloat EXRPixelType = 2
+)
+
+type EXRCompression uint8
+
+const (
+	EXRNoCompression  EXRCompression = 0
+	EXRZIPCompression EXRCompression = 3
+)
+
+// linesPerChunk is how many scanlines are stored together
+func (c EXRCompression) linesPerChunk() int {
+	if c == EXRZIPCompression {
+		return 16
+	}
+
+	return 1
+}
+
+// EXRLayer is a canvas stored as named channels. Channels names the red,
+// green and blue components of the canvas in order, so a depth layer uses
+// just the red component as "Z". Channels of a layer other than the default
+// unnamed one are stored as "<layer>.<channel>". A canvas with an alpha
+// channel also stores it as "A".
+type EXRLayer struct {
+	Name      string
+	Canvas    *Canvas
+	PixelType EXRPixelType
+	Channels  []string
+}
+
+// EXRImage is a scanline OpenEXR image with one or more layers of the same
+// size
+type EXRImage struct {
+	Width       int
+	Height      int
+	Compression EXRCompression
+	Layers      []*EXRLayer
+}
+
+func NewEXRImage(width, height int) *EXRImage {
+	return &EXRImage{
+		Width:       width,
+		Height:      height,
+		Compression: EXRZIPCompression,
+	}
+}
+
+func (e *EXRImage) SetCompression(c EXRCompression) *EXRImage {
+	e.Compression = c
+
+	return e
+}
+
+// AddLayer adds a canvas as a layer, with R, G and B channels unless other
+// channel names are given
+func (e *EXRImage) AddLayer(name string, canvas *Canvas, pixelType EXRPixelType, channels ...string) *EXRImage {
+	if len(channels) == 0 {
+		channels = []string{"R", "G", "B"}
+	}
+
+	e.Layers = append(e.Layers, &EXRLayer{
+		Name:      name,
+		Canvas:    canvas,
+		PixelType: pixelType,
+		Channels:  channels,
+	})
+
+	return e
+}
+
+// Layer returns the layer with a name, or nil
+func (e *EXRImage) Layer(name string) *EXRLayer {
+	for _, l := range e.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// SaveEXR writes the canvas as a half float OpenEXR file
+func (c *Canvas) SaveEXR(filename string) error {
+	return NewEXRImage(c.Width, c.Height).AddLayer("", c, EXRHalf).Save(filename)
+}
+
+// SaveEXR writes the beauty render and the auxiliary buffers as layers of a
+// single OpenEXR file. Colors are half floats, data buffers full floats.
+func (rr *RenderResult) SaveEXR(filename string) error {
+	e := NewEXRImage(rr.Beauty.Width, rr.Beauty.Height).AddLayer("", rr.Beauty, EXRHalf)
+
+	if rr.Albedo != nil {
+		e.AddLayer("albedo", rr.Albedo, EXRHalf)
+		e.AddLayer("depth", rr.Depth, EXRFloat, "Z")
+		e.AddLayer("normal", rr.Normal, EXRFloat, "X", "Y", "Z")
+		e.AddLayer("position", rr.Position, EXRFloat, "X", "Y", "Z")
+		e.AddLayer("objectid", rr.ObjectID, EXRFloat, "id")
+		e.AddLayer("materialid", rr.MaterialID, EXRFloat, "id")
+		e.AddLayer("variance", rr.Variance, EXRFloat)
+	}
+
+	return e.Save(filename)
+}
+
+func (e *EXRImage) Save(filename string) error {
+	return saveCanvas(filename, e.Write)
+}
+
+// exrAlpha is the component of an exrChannel that holds the canvas alpha
+const exrAlpha = 3
+
+// exrChannel is one stored channel and where its values come from
+type exrChannel struct {
+	name      string
+	pixelType EXRPixelType
+	canvas    *Canvas
+	component int
+}
+
+func (c exrChannel) size() int {
+	if c.pixelType == EXRHalf {
+		return 2
+	}
+
+	return 4
+}
+
+func (c exrChannel) value(x, y int) float64 {
+	if c.component == exrAlpha {
+		return c.canvas.GetAlpha(x, y)
+	}
+
+	p := c.canvas.GetPixel(x, y)
+
+	return [3]float64{p.R, p.G, p.B}[c.component]
+}
+
+// channels lists every channel sorted by name, as the format requires
+func (e *EXRImage) channels() ([]exrChannel, error) {
+	var channels []exrChannel
+	seen := make(map[string]bool)
+
+	for _, l := range e.Layers {
+		if l.Canvas.Width != e.Width || l.Canvas.Height != e.Height {
+			return nil, fmt.Errorf("layer %q is %dx%d, want %dx%d", l.Name, l.Canvas.Width, l.Canvas.Height, e.Width, e.Height)
+		}
+
+		if len(l.Channels) > 3 {
+			return nil, fmt.Errorf("layer %q has more than 3 channels", l.Name)
+		}
+
+		for i, ch := range l.Channels {
+			name := ch
+			if l.Name != "" {
+				name = l.Name + "." + ch
+			}
+
+			if seen[name] {
+				return nil, fmt.Errorf("duplicate channel %q", name)
+			}
+			seen[name] = true
+
+			channels = append(channels, exrChannel{name, l.PixelType, l.Canvas, i})
+		}
+
+		if l.Canvas.Alpha != nil {
+			name := "A"
+			if l.Name != "" {
+				name = l.Name + ".A"
+			}
+
+			if seen[name] {
+				return nil, fmt.Errorf("duplicate channel %q", name)
+			}
+			seen[name] = true
+
+			channels = append(channels, exrChannel{name, l.PixelType, l.Canvas, exrAlpha})
+		}
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].name < channels[j].name
+	})
+
+	return channels, nil
+}
+
+// Write encodes the image as a single part scanline OpenEXR file
+func (e *EXRImage) Write(w io.Writer) error {
+	channels, err := e.channels()
+	if err != nil {
+		return err
+	}
+
+	if e.Compression != EXRNoCompression && e.Compression != EXRZIPCompression {
+		return fmt.Errorf("unsupported EXR compression %d", e.Compression)
+	}
+
+	var header bytes.Buffer
+	version := uint32(2)
+
+	le := binary.LittleEndian
+	binary.Write(&header, le, uint32(exrMagic))
+	binary.Write(&header, le, version)
+
+	var chlist bytes.Buffer
+	for _, ch := range channels {
+		if len(ch.name) > 31 {
+			version |= exrLongNames
+		}
+
+		chlist.WriteString(ch.name)
+		chlist.WriteByte(0)
+		binary.Write(&chlist, le, int32(ch.pixelType))
+		chlist.Write([]byte{0, 0, 0, 0})
+		binary.Write(&chlist, le, int32(1))
+		binary.Write(&chlist, le, int32(1))
+	}
+	chlist.WriteByte(0)
+
+	box := func(b *bytes.Buffer) {
+		binary.Write(b, le, [4]int32{0, 0, int32(e.Width - 1), int32(e.Height - 1)})
+	}
+
+	var window bytes.Buffer
+	box(&window)
+
+	var float, center bytes.Buffer
+	binary.Write(&float, le, float32(1))
+	binary.Write(&center, le, [2]float32{0, 0})
+
+	attributes := []struct {
+		name, kind string
+		value      []byte
+	}{
+		{"channels", "chlist", chlist.Bytes()},
+		{"compression", "compression", []byte{byte(e.Compression)}},
+		{"dataWindow", "box2i", window.Bytes()},
+		{"displayWindow", "box2i", window.Bytes()},
+		{"lineOrder", "lineOrder", []byte{0}},
+		{"pixelAspectRatio", "float", float.Bytes()},
+		{"screenWindowCenter", "v2f", center.Bytes()},
+		{"screenWindowWidth", "float", float.Bytes()},
+	}
+
+	for _, a := range attributes {
+		header.WriteString(a.name)
+		header.WriteByte(0)
+		header.WriteString(a.kind)
+		header.WriteByte(0)
+		binary.Write(&header, le, int32(len(a.value)))
+		header.Write(a.value)
+	}
+	header.WriteByte(0)
+
+	// The version is only known after every channel name has been seen
+	headerBytes := header.Bytes()
+	le.PutUint32(headerBytes[4:], version)
+
+	linesPerChunk := e.Compression.linesPerChunk()
+	chunkCount := (e.Height + linesPerChunk - 1) / linesPerChunk
+
+	chunks := make([][]byte, chunkCount)
+	for i := range chunks {
+		chunks[i], err = e.encodeChunk(channels, i*linesPerChunk, linesPerChunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write(headerBytes); err != nil {
+		return err
+	}
+
+	offset := uint64(len(headerBytes) + 8*chunkCount)
+	for _, chunk := range chunks {
+		if err := binary.Write(w, le, offset); err != nil {
+			return err
+		}
+
+		offset += uint64(8 + len(chunk))
+	}
+
+	for i, chunk := range chunks {
+		if err := binary.Write(w, le, [2]int32{int32(i * linesPerChunk), int32(len(chunk))}); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeChunk stores scanlines one after another, each holding all values
+// of the first channel, then all of the second and so on
+func (e *EXRImage) encodeChunk(channels []exrChannel, startY, lines int) ([]byte, error) {
+	var raw bytes.Buffer
+
+	for y := startY; y < startY+lines && y < e.Height; y++ {
+		for _, ch := range channels {
+			for x := 0; x < e.Width; x++ {
+				v := ch.value(x, y)
+
+				if ch.pixelType == EXRHalf {
+					binary.Write(&raw, binary.LittleEndian, floatToHalf(float32(v)))
+				} else {
+					binary.Write(&raw, binary.LittleEndian, float32(v))
+				}
+			}
+		}
+	}
+
+	if e.Compression == EXRNoCompression {
+		return raw.Bytes(), nil
+	}
+
+	compressed, err := exrZIPCompress(raw.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks that do not shrink are stored uncompressed
+	if len(compressed) >= raw.Len() {
+		return raw.Bytes(), nil
+	}
+
+	return compressed, nil
+}
+
+// exrZIPCompress splits the bytes into even and odd halves and stores the
+// difference between neighbouring bytes before deflating, which compresses
+// smooth images much better
+func exrZIPCompress(data []byte) ([]byte, error) {
+	n := len(data)
+	tmp := make([]byte, n)
+
+	half := (n + 1) / 2
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			tmp[i/2] = data[i]
+		} else {
+			tmp[half+i/2] = data[i]
+		}
+	}
+
+	for i := n - 1; i > 0; i-- {
+		tmp[i] = byte(int(tmp[i]) - int(tmp[i-1]) + 128)
+	}
+
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+
+	if _, err := zw.Write(tmp); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func exrZIPDecompress(data []byte, size int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmp) != size {
+		return nil, fmt.Errorf("got %d bytes after decompressing, want %d", len(tmp), size)
+	}
+
+	for i := 1; i < size; i++ {
+		tmp[i] = byte(int(tmp[i-1]) + int(tmp[i]) - 128)
+	}
+
+	out := make([]byte, size)
+	half := (size + 1) / 2
+	for i := 0; i < size; i++ {
+		if i%2 == 0 {
+			out[i] = tmp[i/2]
+		} else {
+			out[i] = tmp[half+i/2]
+		}
+	}
+
+	return out, nil
+}
+
+func LoadEXR(filename string) (*EXRImage, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadEXR(f)
+}
+
+// ReadEXR reads the kind of files Write produces: single part scanline
+// images with half or float channels, without compression or with ZIP.
+// Channels are grouped into layers by the part of their name before the
+// last dot.
+func ReadEXR(r io.Reader) (*EXRImage, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	er := &exrReader{data: data}
+
+	if er.uint32() != exrMagic {
+		return nil, errors.New("not an OpenEXR file")
+	}
+
+	version := er.uint32()
+	if version&0xff != 2 || version&^uint32(0xff|exrLongNames) != 0 {
+		return nil, fmt.Errorf("unsupported EXR version %#x", version)
+	}
+
+	var channels []exrChannel
+	var window [4]int32
+	compression := EXRNoCompression
+	hasWindow := false
+
+	for {
+		name := er.string()
+		if name == "" {
+			break
+		}
+
+		er.string()
+		size := int(er.uint32())
+		value := er.bytes(size)
+
+		if er.err != nil {
+			break
+		}
+
+		vr := &exrReader{data: value}
+
+		switch name {
+		case "channels":
+			for {
+				chName := vr.string()
+				if chName == "" {
+					break
+				}
+
+				pixelType := EXRPixelType(vr.uint32())
+				vr.bytes(4)
+				xSampling, ySampling := vr.uint32(), vr.uint32()
+
+				if pixelType != EXRHalf && pixelType != EXRFloat {
+					return nil, fmt.Errorf("unsupported EXR pixel type %d", pixelType)
+				}
+
+				if xSampling != 1 || ySampling != 1 {
+					return nil, errors.New("subsampled EXR channels are not supported")
+				}
+
+				channels = append(channels, exrChannel{name: chName, pixelType: pixelType})
+			}
+		case "compression":
+			if len(value) != 1 {
+				return nil, fmt.Errorf("invalid EXR attribute %q", name)
+			}
+
+			compression = EXRCompression(value[0])
+		case "dataWindow":
+			for i := range window {
+				window[i] = int32(vr.uint32())
+			}
+			hasWindow = true
+		case "lineOrder":
+			if len(value) != 1 {
+				return nil, fmt.Errorf("invalid EXR attribute %q", name)
+			}
+
+			if value[0] != 0 {
+				return nil, errors.New("only increasing Y line order is supported")
+			}
+		}
+
+		if vr.err != nil {
+			return nil, fmt.Errorf("invalid EXR attribute %q", name)
+		}
+	}
+
+	if er.err != nil || !hasWindow || len(channels) == 0 {
+		return nil, errors.New("invalid EXR header")
+	}
+
+	if compression != EXRNoCompression && compression != EXRZIPCompression {
+		return nil, fmt.Errorf("unsupported EXR compression %d", compression)
+	}
+
+	// The window corners are int32, so the size is computed in int64
+	width64 := int64(window[2]) - int64(window[0]) + 1
+	height64 := int64(window[3]) - int64(window[1]) + 1
+
+	if width64 > maxImagePixels || height64 > maxImagePixels {
+		return nil, fmt.Errorf("EXR image %dx%d is too large", width64, height64)
+	}
+
+	width, height := int(width64), int(height64)
+
+	if _, err := imagePixels("EXR", width, height); err != nil {
+		return nil, err
+	}
+
+	linesPerChunk := compression.linesPerChunk()
+	chunkCount := (height + linesPerChunk - 1) / linesPerChunk
+
+	if chunkCount*8 > len(data)-er.pos {
+		return nil, errors.New("truncated EXR offset table")
+	}
+
+	img := NewEXRImage(width, height).SetCompression(compression)
+	img.addChannelLayers(channels)
+
+	offsets := make([]uint64, chunkCount)
+	for i := range offsets {
+		offsets[i] = er.uint64()
+	}
+
+	if er.err != nil {
+		return nil, errors.New("truncated EXR offset table")
+	}
+
+	for _, offset := range offsets {
+		if offset >= uint64(len(data)) {
+			return nil, errors.New("invalid EXR chunk offset")
+		}
+
+		cr := &exrReader{data: data, pos: int(offset)}
+		y := int(int32(cr.uint32())) - int(window[1])
+		size := int(cr.uint32())
+		chunk := cr.bytes(size)
+
+		if cr.err != nil || y < 0 || y >= height {
+			return nil, errors.New("invalid EXR chunk")
+		}
+
+		lines := linesPerChunk
+		if y+lines > height {
+			lines = height - y
+		}
+
+		rawSize := 0
+		for _, ch := range channels {
+			rawSize += ch.size() * width * lines
+		}
+
+		if compression == EXRZIPCompression && size < rawSize {
+			chunk, err = exrZIPDecompress(chunk, rawSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(chunk) != rawSize {
+			return nil, errors.New("invalid EXR chunk size")
+		}
+
+		pr := &exrReader{data: chunk}
+
+		for line := y; line < y+lines; line++ {
+			for _, ch := range channels {
+				for x := 0; x < width; x++ {
+					var v float64
+					if ch.pixelType == EXRHalf {
+						v = float64(halfToFloat(pr.uint16()))
+					} else {
+						v = float64(math.Float32frombits(pr.uint32()))
+					}
+
+					ch.set(x, line, v)
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// addChannelLayers creates a layer for every channel name prefix and points
+// the channels at the canvas component they fill. Layers with R, G and B
+// channels keep those in their components, load A into the canvas alpha and
+// skip any other channels. Other layers fill components in name order and
+// keep at most three channels.
+func (e *EXRImage) addChannelLayers(channels []exrChannel) {
+	names := make([]string, len(channels))
+
+	for i, ch := range channels {
+		layerName, channelName := "", ch.name
+		if dot := strings.LastIndex(ch.name, "."); dot >= 0 {
+			layerName, channelName = ch.name[:dot], ch.name[dot+1:]
+		}
+
+		if e.Layer(layerName) == nil {
+			e.Layers = append(e.Layers, &EXRLayer{
+				Name:      layerName,
+				Canvas:    NewCanvas(e.Width, e.Height),
+				PixelType: ch.pixelType,
+			})
+		}
+
+		layer := e.Layer(layerName)
+		layer.Channels = append(layer.Channels, channelName)
+		names[i] = channelName
+		channels[i].canvas = layer.Canvas
+	}
+
+	rgb := map[string]int{"R": 0, "G": 1, "B": 2}
+
+	for _, layer := range e.Layers {
+		found := 0
+		for _, name := range layer.Channels {
+			if _, ok := rgb[name]; ok {
+				found++
+			}
+		}
+
+		hasAlpha := false
+
+		// Channels are sorted by name, so look the color channels up by name
+		if found == len(rgb) {
+			for _, name := range layer.Channels {
+				hasAlpha = hasAlpha || name == "A"
+			}
+
+			layer.Channels = []string{"R", "G", "B"}
+		} else if len(layer.Channels) > 3 {
+			layer.Channels = layer.Channels[:3]
+		}
+
+		for i := range channels {
+			if channels[i].canvas != layer.Canvas {
+				continue
+			}
+
+			channels[i].canvas = nil
+
+			if hasAlpha && names[i] == "A" {
+				channels[i].canvas = layer.Canvas
+				channels[i].component = exrAlpha
+			}
+
+			for component, name := range layer.Channels {
+				if name == names[i] {
+					channels[i].canvas = layer.Canvas
+					channels[i].component = component
+				}
+			}
+		}
+	}
+}
+
+func (c exrChannel) set(x, y int, v float64) {
+	if c.canvas == nil {
+		return
+	}
+
+	if c.component == exrAlpha {
+		c.canvas.SetAlpha(x, y, v)
+		return
+	}
+
+	p := c.canvas.GetPixel(x, y)
+
+	switch c.component {
+	case 0:
+		p.R = v
+	case 1:
+		p.G = v
+	case 2:
+		p.B = v
+	}
+
+	c.canvas.SetPixel(x, y, p)
+}
+
+// exrReader reads little endian values and remembers the first error
+type exrReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *exrReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b
+}
+
+func (r *exrReader) uint16() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+func (r *exrReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+func (r *exrReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.bytes(8))
+}
+
+func (r *exrReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+
+	return s
+}
+
+// floatToHalf converts to a 16-bit float, rounding to the nearest even value
+func floatToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int((bits >> 23) & 0xff)
+	mantissa := bits & 0x7fffff
+
+	if exponent == 0xff {
+		if mantissa != 0 {
+			return sign | 0x7e00
+		}
+
+		return sign | 0x7c00
+	}
+
+	e := exponent - 127 + 15
+
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+
+	if e <= 0 {
+		if e < -10 {
+			return sign
+		}
+
+		mantissa |= 0x800000
+		shift := uint(14 - e)
+		half := uint16(mantissa >> shift)
+
+		rest := mantissa & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+
+		if rest > halfway || (rest == halfway && half&1 == 1) {
+			half++
+		}
+
+		return sign | half
+	}
+
+	half := sign | uint16(e)<<10 | uint16(mantissa>>13)
+	rest := mantissa & 0x1fff
+
+	// Rounding up may carry into the exponent, which is still correct
+	if rest > 0x1000 || (rest == 0x1000 && half&1 == 1) {
+		half++
+	}
+
+	return half
+}
+
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h & 0x3ff)
+
+	switch exponent {
+	case 0:
+		v := float32(mantissa) * float32(math.Pow(2, -24))
+		if sign != 0 {
+			v = -v
+		}
+
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	default:
+		return math.Float32frombits(sign | (exponent-15+127)<<23 | mantissa<<13)
+	}
+}
@@ -0,0 +1,421 @@
+package raytracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestHalfFloatConversion(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		value float32
+		half  uint16
+	}{
+		{"Zero", 0, 0x0000},
+		{"One", 1, 0x3c00},
+		{"Minus two", -2, 0xc000},
+		{"One third", 1.0 / 3, 0x3555},
+		{"Largest half", 65504, 0x7bff},
+		{"Overflow", 1e6, 0x7c00},
+		{"Smallest subnormal", float32(math.Pow(2, -24)), 0x0001},
+		{"Infinity", float32(math.Inf(1)), 0x7c00},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := floatToHalf(tC.value); got != tC.half {
+				t.Errorf("Got %#04x, want %#04x", got, tC.half)
+			}
+		})
+	}
+}
+
+func TestHalfFloatRoundTrip(t *testing.T) {
+	for h := 0; h < 0x7c00; h++ {
+		if got := floatToHalf(halfToFloat(uint16(h))); got != uint16(h) {
+			t.Fatalf("Got %#04x, want %#04x", got, h)
+		}
+	}
+}
+
+func TestEXRZIPRoundTrip(t *testing.T) {
+	data := []byte("an odd number of bytes with some repetition repetition")
+
+	compressed, err := exrZIPCompress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := exrZIPDecompress(compressed, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("Got %q, want %q", got, data)
+	}
+}
+
+func TestEXRHeader(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := NewEXRImage(2, 2).AddLayer("", NewCanvas(2, 2), EXRHalf).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+
+	if got := binary.LittleEndian.Uint32(data); got != exrMagic {
+		t.Errorf("Got magic %d, want %d", got, exrMagic)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[4:]); got != 2 {
+		t.Errorf("Got version %d, want 2", got)
+	}
+
+	// Channels are stored in alphabetical order
+	b, g, r := bytes.Index(data, []byte("B\x00")), bytes.Index(data, []byte("G\x00")), bytes.Index(data, []byte("R\x00"))
+	if !(b < g && g < r) {
+		t.Errorf("Got channels at %d, %d, %d, want B, G, R in order", b, g, r)
+	}
+}
+
+func TestEXRRoundTrip(t *testing.T) {
+	beauty := hdrTestCanvas(19, 21)
+	depth := NewCanvas(19, 21)
+	normal := NewCanvas(19, 21)
+
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 19; x++ {
+			d := 1 + float64(x)*0.123456789
+			depth.SetPixel(x, y, NewColor(d, d, d))
+			normal.SetPixel(x, y, NewColor(float64(x)/19, -float64(y)/21, 0.25))
+		}
+	}
+
+	testCases := []struct {
+		desc        string
+		compression EXRCompression
+	}{
+		{"No compression", EXRNoCompression},
+		{"ZIP", EXRZIPCompression},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := NewEXRImage(19, 21).
+				SetCompression(tC.compression).
+				AddLayer("", beauty, EXRHalf).
+				AddLayer("depth", depth, EXRFloat, "Z").
+				AddLayer("normal", normal, EXRFloat, "X", "Y", "Z").
+				Write(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := ReadEXR(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if img.Width != 19 || img.Height != 21 || img.Compression != tC.compression {
+				t.Fatalf("Got %dx%d compression %d, want 19x21 compression %d", img.Width, img.Height, img.Compression, tC.compression)
+			}
+
+			if len(img.Layers) != 3 {
+				t.Fatalf("Got %v layers, want 3", len(img.Layers))
+			}
+
+			gotBeauty, gotDepth, gotNormal := img.Layer(""), img.Layer("depth"), img.Layer("normal")
+			if gotBeauty == nil || gotDepth == nil || gotNormal == nil {
+				t.Fatalf("Got layers %v, want beauty, depth and normal", img.Layers)
+			}
+
+			if gotBeauty.PixelType != EXRHalf || gotDepth.PixelType != EXRFloat {
+				t.Errorf("Got pixel types %v and %v, want half and float", gotBeauty.PixelType, gotDepth.PixelType)
+			}
+
+			if got := gotNormal.Channels; len(got) != 3 || got[0] != "X" || got[2] != "Z" {
+				t.Errorf("Got normal channels %v, want [X Y Z]", got)
+			}
+
+			for y := 0; y < 21; y++ {
+				for x := 0; x < 19; x++ {
+					// Half floats keep 11 significant bits
+					if got, want := gotBeauty.Canvas.GetPixel(x, y), beauty.GetPixel(x, y); !colorsClose(got, want, 1e-3) {
+						t.Fatalf("Got beauty %v at %d, %d, want %v", got, x, y, want)
+					}
+
+					if got, want := gotDepth.Canvas.GetPixel(x, y).R, float64(float32(depth.GetPixel(x, y).R)); got != want {
+						t.Fatalf("Got depth %v at %d, %d, want %v", got, x, y, want)
+					}
+
+					want := normal.GetPixel(x, y)
+					if got := gotNormal.Canvas.GetPixel(x, y); !colorsClose(got, want, 1e-6) {
+						t.Fatalf("Got normal %v at %d, %d, want %v", got, x, y, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEXRZIPIsSmaller(t *testing.T) {
+	c := NewCanvas(64, 64)
+	for i := range c.Pixels {
+		c.Pixels[i] = NewColor(0.5, 0.25, 0.125)
+	}
+
+	var plain, zipped bytes.Buffer
+	NewEXRImage(64, 64).SetCompression(EXRNoCompression).AddLayer("", c, EXRHalf).Write(&plain)
+	NewEXRImage(64, 64).AddLayer("", c, EXRHalf).Write(&zipped)
+
+	if zipped.Len() >= plain.Len() {
+		t.Errorf("Got %v bytes compressed, want less than %v", zipped.Len(), plain.Len())
+	}
+}
+
+func TestEXRRejectsMismatchedLayers(t *testing.T) {
+	testCases := []struct {
+		desc string
+		img  *EXRImage
+	}{
+		{"Different size", NewEXRImage(2, 2).AddLayer("", NewCanvas(3, 2), EXRHalf)},
+		{"Duplicate channel", NewEXRImage(2, 2).AddLayer("a", NewCanvas(2, 2), EXRHalf).AddLayer("a", NewCanvas(2, 2), EXRHalf)},
+		{"Too many channels", NewEXRImage(2, 2).AddLayer("", NewCanvas(2, 2), EXRHalf, "R", "G", "B", "A")},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if err := tC.img.Write(&bytes.Buffer{}); err == nil {
+				t.Errorf("Got no error, want one")
+			}
+		})
+	}
+}
+
+func TestRenderResultSaveEXR(t *testing.T) {
+	rr := NewRenderResult(3, 2)
+	rr.SetPixel(1, 1, NewColor(2, 1, 0.5), AOVSample{
+		Depth:    4.5,
+		Normal:   NewVec(0, 1, 0),
+		Albedo:   NewColor(0.25, 0.5, 0.75),
+		ObjectID: 7,
+	})
+
+	filename := filepath.Join(t.TempDir(), "render.exr")
+
+	if err := rr.SaveEXR(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := LoadEXR(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		layer string
+		want  Color
+	}{
+		{"", NewColor(2, 1, 0.5)},
+		{"albedo", NewColor(0.25, 0.5, 0.75)},
+		{"depth", NewColor(4.5, 0, 0)},
+		{"normal", NewColor(0, 1, 0)},
+		{"objectid", NewColor(7, 0, 0)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.layer, func(t *testing.T) {
+			layer := img.Layer(tC.layer)
+			if layer == nil {
+				t.Fatalf("Got no layer %q", tC.layer)
+			}
+
+			if got := layer.Canvas.GetPixel(1, 1); got != tC.want {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestReadEXRRejectsEmptyAttributes(t *testing.T) {
+	for _, name := range []string{"compression", "lineOrder"} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			binary.Write(&buf, binary.LittleEndian, []uint32{exrMagic, 2})
+			buf.WriteString(name + "\x00" + name + "\x00")
+			binary.Write(&buf, binary.LittleEndian, uint32(0))
+			buf.WriteByte(0)
+
+			if _, err := ReadEXR(&buf); err == nil {
+				t.Errorf("Got no error, want one")
+			}
+		})
+	}
+}
+
+// writeEXRHeader writes the header of an uncompressed half float image with
+// the given channels, which must be sorted by name
+func writeEXRHeader(buf *bytes.Buffer, channels []string, window [4]int32) {
+	le := binary.LittleEndian
+
+	attribute := func(name, kind string, value []byte) {
+		buf.WriteString(name + "\x00" + kind + "\x00")
+		binary.Write(buf, le, uint32(len(value)))
+		buf.Write(value)
+	}
+
+	var chlist bytes.Buffer
+	for _, ch := range channels {
+		chlist.WriteString(ch + "\x00")
+		binary.Write(&chlist, le, []int32{int32(EXRHalf), 0, 1, 1})
+	}
+	chlist.WriteByte(0)
+
+	var box bytes.Buffer
+	binary.Write(&box, le, window)
+
+	binary.Write(buf, le, []uint32{exrMagic, 2})
+	attribute("channels", "chlist", chlist.Bytes())
+	attribute("compression", "compression", []byte{0})
+	attribute("dataWindow", "box2i", box.Bytes())
+	attribute("lineOrder", "lineOrder", []byte{0})
+	buf.WriteByte(0)
+}
+
+func TestReadEXRWithAlphaChannel(t *testing.T) {
+	var buf bytes.Buffer
+	writeEXRHeader(&buf, []string{"A", "B", "G", "R"}, [4]int32{0, 0, 0, 0})
+
+	values := []float32{0.5, 0.25, 0.75, 1}
+	binary.Write(&buf, binary.LittleEndian, uint64(buf.Len()+8))
+	binary.Write(&buf, binary.LittleEndian, []int32{0, int32(2 * len(values))})
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, floatToHalf(v))
+	}
+
+	img, err := ReadEXR(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := img.Layer("")
+	if layer == nil {
+		t.Fatal("Got no default layer")
+	}
+
+	got := layer.Canvas.GetPixel(0, 0)
+	want := NewColor(1, 0.75, 0.25)
+
+	if got != want {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	if got := layer.Canvas.GetAlpha(0, 0); got != 0.5 {
+		t.Errorf("Got alpha %v, want 0.5", got)
+	}
+}
+
+func TestReadEXRRejectsInvalidSizes(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		window [4]int32
+	}{
+		{desc: "Empty", window: [4]int32{0, 0, -1, 0}},
+		{desc: "Too large", window: [4]int32{0, 0, 99999, 99999}},
+		{desc: "Overflowing", window: [4]int32{math.MinInt32, 0, math.MaxInt32, 0}},
+		{desc: "Truncated offset table", window: [4]int32{0, 0, 0, 999999}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeEXRHeader(&buf, []string{"B", "G", "R"}, tC.window)
+
+			if _, err := ReadEXR(&buf); err == nil {
+				t.Errorf("Got no error, want one")
+			}
+		})
+	}
+}
+
+func TestEXRAlphaRoundTrip(t *testing.T) {
+	rr := NewRenderResult(3, 1)
+	rr.SetPixel(1, 0, NewColor(0.5, 0.25, 0), AOVSample{Albedo: NewColor(1, 1, 1)})
+	rr.Beauty.SetAlpha(0, 0, 0)
+	rr.Beauty.SetAlpha(1, 0, 0.5)
+
+	dir := t.TempDir()
+
+	testCases := []struct {
+		desc string
+		save func(filename string) error
+	}{
+		{"Canvas", rr.Beauty.SaveEXR},
+		{"Render result", rr.SaveEXR},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			filename := filepath.Join(dir, tC.desc+".exr")
+
+			if err := tC.save(filename); err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := LoadEXR(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			beauty := img.Layer("").Canvas
+
+			for x, want := range []float64{0, 0.5, 1} {
+				if got := beauty.GetAlpha(x, 0); got != want {
+					t.Errorf("Got alpha %v at %d, want %v", got, x, want)
+				}
+			}
+
+			if got, want := beauty.GetPixel(1, 0), NewColor(0.5, 0.25, 0); got != want {
+				t.Errorf("Got %v, want %v", got, want)
+			}
+
+			if albedo := img.Layer("albedo"); albedo != nil && albedo.Canvas.Alpha != nil {
+				t.Errorf("Got alpha on the albedo layer, want none")
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	NewEXRImage(1, 1).AddLayer("", NewCanvas(1, 1), EXRHalf).Write(&buf)
+
+	opaque, err := ReadEXR(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opaque.Layer("").Canvas.Alpha != nil {
+		t.Errorf("Got an alpha channel, want none for an opaque canvas")
+	}
+}
+
+func TestLoadCanvasReadsEXR(t *testing.T) {
+	c := hdrTestCanvas(4, 3)
+	filename := filepath.Join(t.TempDir(), "canvas.exr")
+
+	if err := c.SaveEXR(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadCanvas(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range c.Pixels {
+		if !colorsClose(got.Pixels[i], want, 1e-3) {
+			t.Errorf("Got %v at %d, want %v", got.Pixels[i], i, want)
+		}
+	}
+}
@@ -9,6 +9,9 @@ type Group struct {
 	Items       []Intersectable
 	Parent      Intersectable
 	SavedBounds *BoundingBox
+	// Solid marks a group of triangles as one closed mesh that refraction
+	// enters and leaves as a whole, however it is divided into subgroups
+	Solid bool
 }
 
 func NewGroup() *Group {
@@ -25,6 +28,12 @@ func (g *Group) AddChild(c Intersectable) *Group {
 	return g
 }
 
+func (g *Group) SetSolid(solid bool) *Group {
+	g.Solid = solid
+
+	return g
+}
+
 func (g *Group) SetTransform(m *Matrix) Intersectable {
 	g.Transform = m
 
@@ -40,7 +40,7 @@ func TestAddingChildToGroup(t *testing.T) {
 		t.Error("Invalid item")
 	}
 
-	if s.Parent != g {
+	if s.GetParent() != g {
 		t.Error("Invalid parent")
 	}
 }
@@ -61,8 +61,10 @@ func TestIntersectingRayWithNonEmptyGroup(t *testing.T) {
 	g := NewGroup()
 
 	s1 := NewSphere()
-	s2 := NewSphere().SetTransform(NewTranslation(0, 0, -3))
-	s3 := NewSphere().SetTransform(NewTranslation(5, 0, 0))
+	s2 := NewSphere()
+	s2.SetTransform(NewTranslation(0, 0, -3))
+	s3 := NewSphere()
+	s3.SetTransform(NewTranslation(5, 0, 0))
 	g.AddChild(s1)
 	g.AddChild(s2)
 	g.AddChild(s3)
@@ -0,0 +1,287 @@
+package raytracer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// hdrMagic starts every Radiance file
+const hdrMagic = "#?"
+
+// hdrMinRun is the shortest run worth encoding as a run
+const hdrMinRun = 4
+
+// SaveHDR writes the canvas as a Radiance RGBE .hdr file, keeping the full
+// linear range of the colors
+func (c *Canvas) SaveHDR(filename string) error {
+	return saveCanvas(filename, c.WriteHDR)
+}
+
+// WriteHDR writes the canvas in the Radiance RGBE format with run length
+// encoded scanlines
+func (c *Canvas) WriteHDR(w io.Writer) error {
+	header := fmt.Sprintf("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", c.Height, c.Width)
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	scanline := make([]byte, c.Width*4)
+	component := make([]byte, c.Width)
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			rgbe := toRGBE(c.GetPixel(x, y))
+			copy(scanline[x*4:], rgbe[:])
+		}
+
+		// Scanlines of unusual widths cannot be run length encoded
+		if c.Width < 8 || c.Width > 0x7fff {
+			if _, err := w.Write(scanline); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if _, err := w.Write([]byte{2, 2, byte(c.Width >> 8), byte(c.Width & 0xFF)}); err != nil {
+			return err
+		}
+
+		for i := 0; i < 4; i++ {
+			for x := 0; x < c.Width; x++ {
+				component[x] = scanline[x*4+i]
+			}
+
+			if _, err := w.Write(encodeHDRRuns(component)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadHDR reads a Radiance RGBE .hdr file into a canvas
+func LoadHDR(filename string) (*Canvas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadHDR(f)
+}
+
+// ReadHDR reads a Radiance RGBE image with flat or run length encoded
+// scanlines. Only the standard -Y +X orientation is supported.
+func ReadHDR(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, hdrMagic) {
+		return nil, errors.New("not a Radiance HDR file")
+	}
+
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("reading HDR header: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			break
+		}
+
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported HDR format %q", line)
+		}
+	}
+
+	line, err = br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading HDR resolution: %w", err)
+	}
+
+	var width, height int
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported HDR resolution %q", strings.TrimSpace(line))
+	}
+
+	if _, err := imagePixels("HDR", width, height); err != nil {
+		return nil, err
+	}
+
+	canvas := NewCanvas(width, height)
+	scanline := make([]byte, width*4)
+
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(br, scanline, width); err != nil {
+			return nil, fmt.Errorf("reading HDR scanline %d: %w", y, err)
+		}
+
+		for x := 0; x < width; x++ {
+			var rgbe [4]byte
+			copy(rgbe[:], scanline[x*4:])
+
+			canvas.SetPixel(x, y, fromRGBE(rgbe))
+		}
+	}
+
+	return canvas, nil
+}
+
+func readHDRScanline(r *bufio.Reader, scanline []byte, width int) error {
+	if _, err := io.ReadFull(r, scanline[:4]); err != nil {
+		return err
+	}
+
+	isRLE := width >= 8 && width <= 0x7fff && scanline[0] == 2 && scanline[1] == 2 && scanline[2]&0x80 == 0
+
+	if !isRLE {
+		_, err := io.ReadFull(r, scanline[4:])
+		return err
+	}
+
+	if int(scanline[2])<<8|int(scanline[3]) != width {
+		return errors.New("scanline width mismatch")
+	}
+
+	component := make([]byte, width)
+
+	for i := 0; i < 4; i++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			if count > 128 {
+				n := int(count) - 128
+
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+
+				if x+n > width {
+					return errors.New("run past end of scanline")
+				}
+
+				for j := 0; j < n; j++ {
+					component[x+j] = value
+				}
+
+				x += n
+			} else {
+				n := int(count)
+
+				if n == 0 || x+n > width {
+					return errors.New("invalid literal run")
+				}
+
+				if _, err := io.ReadFull(r, component[x:x+n]); err != nil {
+					return err
+				}
+
+				x += n
+			}
+		}
+
+		for x := 0; x < width; x++ {
+			scanline[x*4+i] = component[x]
+		}
+	}
+
+	return nil
+}
+
+// encodeHDRRuns run length encodes one component of a scanline. Runs are a
+// byte of 128 plus the length followed by the value, anything else is a byte
+// with the count followed by that many literal values.
+func encodeHDRRuns(data []byte) []byte {
+	var out bytes.Buffer
+	n := len(data)
+
+	for cur := 0; cur < n; {
+		begRun := cur
+		runCount, oldRunCount := 0, 0
+
+		// Find the next run that is long enough to be worth encoding
+		for runCount < hdrMinRun && begRun < n {
+			begRun += runCount
+			oldRunCount = runCount
+			runCount = 1
+
+			for begRun+runCount < n && runCount < 127 && data[begRun] == data[begRun+runCount] {
+				runCount++
+			}
+		}
+
+		// A short run right before it is still cheaper as a run
+		if oldRunCount > 1 && oldRunCount == begRun-cur {
+			out.WriteByte(byte(128 + oldRunCount))
+			out.WriteByte(data[cur])
+			cur = begRun
+		}
+
+		for cur < begRun {
+			literal := begRun - cur
+			if literal > 128 {
+				literal = 128
+			}
+
+			out.WriteByte(byte(literal))
+			out.Write(data[cur : cur+literal])
+			cur += literal
+		}
+
+		if runCount >= hdrMinRun {
+			out.WriteByte(byte(128 + runCount))
+			out.WriteByte(data[begRun])
+			cur += runCount
+		}
+	}
+
+	return out.Bytes()
+}
+
+// toRGBE stores a color as three 8-bit mantissas sharing one exponent
+func toRGBE(c Color) [4]byte {
+	max := math.Max(math.Max(c.R, c.G), c.B)
+
+	if max < 1e-32 {
+		return [4]byte{}
+	}
+
+	mantissa, exponent := math.Frexp(max)
+	scale := mantissa * 256 / max
+
+	channel := func(v float64) byte {
+		return byte(math.Max(v*scale, 0))
+	}
+
+	return [4]byte{channel(c.R), channel(c.G), channel(c.B), byte(exponent + 128)}
+}
+
+func fromRGBE(rgbe [4]byte) Color {
+	if rgbe[3] == 0 {
+		return colorBlack
+	}
+
+	f := math.Ldexp(1, int(rgbe[3])-(128+8))
+
+	return NewColor(
+		(float64(rgbe[0])+0.5)*f,
+		(float64(rgbe[1])+0.5)*f,
+		(float64(rgbe[2])+0.5)*f,
+	)
+}
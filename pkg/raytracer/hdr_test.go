@@ -0,0 +1,141 @@
+package raytracer
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hdrTestCanvas has runs, literals and values far outside [0, 1]
+func hdrTestCanvas(width, height int) *Canvas {
+	c := NewCanvas(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				c.SetPixel(x, y, NewColor(1000, 0.5, 0.001))
+			} else {
+				v := float64(x*y) / 7
+				c.SetPixel(x, y, NewColor(v, v/2, 0))
+			}
+		}
+	}
+
+	return c
+}
+
+// colorsClose compares colors relative to their largest channel, which is
+// the precision of formats with a shared exponent
+func colorsClose(a, b Color, tolerance float64) bool {
+	scale := math.Max(math.Max(math.Max(b.R, b.G), b.B), 1e-3)
+
+	close := func(x, y float64) bool {
+		return math.Abs(x-y) <= tolerance*scale
+	}
+
+	return close(a.R, b.R) && close(a.G, b.G) && close(a.B, b.B)
+}
+
+func TestHDRRoundTrip(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		width, height int
+	}{
+		{desc: "Run length encoded", width: 300, height: 4},
+		{desc: "Too narrow for runs", width: 5, height: 3},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := hdrTestCanvas(tC.width, tC.height)
+
+			var buf bytes.Buffer
+			if err := c.WriteHDR(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadHDR(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Width != c.Width || got.Height != c.Height {
+				t.Fatalf("Got %vx%v, want %vx%v", got.Width, got.Height, c.Width, c.Height)
+			}
+
+			for i := range c.Pixels {
+				want := c.Pixels[i]
+
+				if !colorsClose(got.Pixels[i], want, 0.01) {
+					t.Fatalf("Pixel %d, got %v, want %v", i, got.Pixels[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHDRRunLengthEncodingCompresses(t *testing.T) {
+	c := uniformCanvas(256, 16, NewColor(2, 2, 2))
+
+	var buf bytes.Buffer
+	if err := c.WriteHDR(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() > 256*16 {
+		t.Errorf("Got %v bytes, want a compressed file", buf.Len())
+	}
+}
+
+func TestEncodeHDRRuns(t *testing.T) {
+	data := []byte{1, 2, 3, 3, 5, 5, 5, 5, 5, 6}
+
+	got := encodeHDRRuns(data)
+	want := []byte{4, 1, 2, 3, 3, 128 + 5, 5, 1, 6}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestReadInvalidHDR(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+	}{
+		{desc: "Not HDR", input: "P3\n1 1\n255\n"},
+		{desc: "XYZE", input: "#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00"},
+		{desc: "Flipped", input: "#?RADIANCE\n\n+Y 1 +X 1\n\x00\x00\x00\x00"},
+		{desc: "Truncated", input: "#?RADIANCE\n\n-Y 2 +X 1\n\x00\x00\x00\x00"},
+		{desc: "Empty", input: "#?RADIANCE\n\n-Y 0 +X 1\n"},
+		{desc: "Too large", input: "#?RADIANCE\n\n-Y 100000 +X 100000\n\x00\x00\x00\x00"},
+		{desc: "Overflowing", input: "#?RADIANCE\n\n-Y 4294967296 +X 4294967296\n\x00\x00\x00\x00"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := ReadHDR(strings.NewReader(tC.input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadCanvasReadsHDR(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sky.hdr")
+
+	if err := uniformCanvas(16, 8, NewColor(4, 2, 1)).SaveHDR(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	em, err := LoadEnvironmentMap(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := em.ColorAt(NewVec(0, 1, 0))
+
+	if !colorsClose(got, NewColor(4, 2, 1), 0.01) {
+		t.Errorf("Got %v, want %v", got, NewColor(4, 2, 1))
+	}
+}
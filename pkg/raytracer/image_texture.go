@@ -0,0 +1,153 @@
+package raytracer
+
+import (
+	"errors"
+	"math"
+)
+
+// WrapMode decides what an image texture shows outside of [0, 1)
+type WrapMode int
+
+const (
+	// WrapRepeat tiles the image
+	WrapRepeat WrapMode = iota
+	// WrapClamp stretches the edge pixels outwards
+	WrapClamp
+	// WrapMirror tiles the image, flipping every other tile so edges meet
+	WrapMirror
+)
+
+// ImageTexture looks up an image by the UV coordinates of the object it is
+// on. Coordinates are scaled and then offset before the lookup, with v = 0 at
+// the bottom row of the image.
+type ImageTexture struct {
+	Image    *Canvas
+	Wrap     WrapMode
+	Bilinear bool
+	OffsetU  float64
+	OffsetV  float64
+	ScaleU   float64
+	ScaleV   float64
+}
+
+// NewImageTexture panics when the image has no pixels to look up
+func NewImageTexture(image *Canvas) *ImageTexture {
+	if image.Width <= 0 || image.Height <= 0 {
+		panic("Image texture image is empty")
+	}
+
+	return &ImageTexture{
+		Image:    image,
+		Wrap:     WrapRepeat,
+		Bilinear: true,
+		ScaleU:   1,
+		ScaleV:   1,
+	}
+}
+
+// LoadImageTexture reads an sRGB PNG or JPEG file, or a linear HDR file, as
+// a color texture
+func LoadImageTexture(filename string) (*ImageTexture, error) {
+	return LoadImageTextureWithTransfer(filename, SRGB)
+}
+
+// LoadImageTextureWithTransfer reads an image decoding its values with a
+// transfer function. Use Linear for data like roughness or reflection maps.
+func LoadImageTextureWithTransfer(filename string, transfer TransferFunction) (*ImageTexture, error) {
+	image, err := LoadCanvasWithTransfer(filename, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	if image.Width <= 0 || image.Height <= 0 {
+		return nil, errors.New("image texture image is empty")
+	}
+
+	return NewImageTexture(image), nil
+}
+
+func (it *ImageTexture) SetWrap(w WrapMode) *ImageTexture {
+	it.Wrap = w
+
+	return it
+}
+
+// SetBilinear turns bilinear filtering on or off, off picks the nearest pixel
+func (it *ImageTexture) SetBilinear(b bool) *ImageTexture {
+	it.Bilinear = b
+
+	return it
+}
+
+func (it *ImageTexture) SetOffset(u, v float64) *ImageTexture {
+	it.OffsetU = u
+	it.OffsetV = v
+
+	return it
+}
+
+// SetScale sets how many times the image fits across the UV square
+func (it *ImageTexture) SetScale(u, v float64) *ImageTexture {
+	it.ScaleU = u
+	it.ScaleV = v
+
+	return it
+}
+
+func (it *ImageTexture) ColorAt(object Intersectable, worldPoint Point) Color {
+	u, v := UVAt(object, worldPoint)
+
+	return it.ColorAtUV(u, v)
+}
+
+// ColorAtUV returns the filtered color of the image at texture coordinates
+func (it *ImageTexture) ColorAtUV(u, v float64) Color {
+	u = u*it.ScaleU + it.OffsetU
+	v = v*it.ScaleV + it.OffsetV
+
+	x := u * float64(it.Image.Width)
+	y := (1 - v) * float64(it.Image.Height)
+
+	if !it.Bilinear {
+		return it.pixel(int(math.Floor(x)), int(math.Floor(y)))
+	}
+
+	// Pixel centers sit at half pixels
+	x -= 0.5
+	y -= 0.5
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	top := it.pixel(ix, iy).MulFloat(1 - fx).Add(it.pixel(ix+1, iy).MulFloat(fx))
+	bottom := it.pixel(ix, iy+1).MulFloat(1 - fx).Add(it.pixel(ix+1, iy+1).MulFloat(fx))
+
+	return top.MulFloat(1 - fy).Add(bottom.MulFloat(fy))
+}
+
+func (it *ImageTexture) pixel(x, y int) Color {
+	return it.Image.GetPixel(wrapIndex(x, it.Image.Width, it.Wrap), wrapIndex(y, it.Image.Height, it.Wrap))
+}
+
+// wrapIndex brings a pixel index into [0, size)
+func wrapIndex(i, size int, mode WrapMode) int {
+	switch mode {
+	case WrapClamp:
+		return clampIndex(i, size)
+	case WrapMirror:
+		i = positiveMod(i, 2*size)
+
+		if i >= size {
+			return 2*size - 1 - i
+		}
+
+		return i
+	default:
+		return positiveMod(i, size)
+	}
+}
+
+func positiveMod(i, n int) int {
+	return ((i % n) + n) % n
+}
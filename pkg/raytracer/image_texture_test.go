@@ -0,0 +1,238 @@
+package raytracer
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// blackWhite is a two pixel wide image, black on the left
+func blackWhite() *Canvas {
+	c := NewCanvas(2, 1)
+	c.SetPixel(1, 0, white)
+
+	return c
+}
+
+func TestWrapIndex(t *testing.T) {
+	testCases := []struct {
+		desc string
+		i    int
+		mode WrapMode
+		want int
+	}{
+		{"Repeat inside", 2, WrapRepeat, 2},
+		{"Repeat past the end", 5, WrapRepeat, 1},
+		{"Repeat before the start", -1, WrapRepeat, 3},
+		{"Clamp past the end", 5, WrapClamp, 3},
+		{"Clamp before the start", -1, WrapClamp, 0},
+		{"Mirror past the end", 4, WrapMirror, 3},
+		{"Mirror further past the end", 6, WrapMirror, 1},
+		{"Mirror before the start", -1, WrapMirror, 0},
+		{"Mirror a whole period later", 9, WrapMirror, 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := wrapIndex(tC.i, 4, tC.mode); got != tC.want {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestImageTextureRejectsEmptyImages(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		width, height int
+	}{
+		{"No pixels", 0, 0},
+		{"No columns", 0, 4},
+		{"No rows", 4, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+
+			NewImageTexture(NewCanvas(tC.width, tC.height))
+		})
+	}
+}
+
+func TestImageTextureNearest(t *testing.T) {
+	c := NewCanvas(2, 2)
+	c.SetPixel(0, 0, NewColor(1, 0, 0))
+	c.SetPixel(1, 0, NewColor(0, 1, 0))
+	c.SetPixel(0, 1, NewColor(0, 0, 1))
+	c.SetPixel(1, 1, white)
+
+	tex := NewImageTexture(c).SetBilinear(false)
+
+	testCases := []struct {
+		desc string
+		u, v float64
+		want Color
+	}{
+		{"Top left", 0.25, 0.75, NewColor(1, 0, 0)},
+		{"Top right", 0.75, 0.75, NewColor(0, 1, 0)},
+		{"Bottom left", 0.25, 0.25, NewColor(0, 0, 1)},
+		{"Bottom right", 0.75, 0.25, white},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tex.ColorAtUV(tC.u, tC.v); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestImageTextureBilinear(t *testing.T) {
+	gray := NewColor(0.5, 0.5, 0.5)
+
+	testCases := []struct {
+		desc string
+		tex  *ImageTexture
+		u    float64
+		want Color
+	}{
+		{"Pixel center", NewImageTexture(blackWhite()), 0.25, black},
+		{"Between pixels", NewImageTexture(blackWhite()), 0.5, gray},
+		{"Quarter of the way", NewImageTexture(blackWhite()), 0.375, NewColor(0.25, 0.25, 0.25)},
+		{"Repeat blends across the edge", NewImageTexture(blackWhite()), 0, gray},
+		{"Clamp keeps the edge", NewImageTexture(blackWhite()).SetWrap(WrapClamp), 0, black},
+		{"Mirror reflects the edge", NewImageTexture(blackWhite()).SetWrap(WrapMirror), 0, black},
+		{"Scale tiles the image", NewImageTexture(blackWhite()).SetScale(2, 1), 0.375, white},
+		{"Offset shifts the image", NewImageTexture(blackWhite()).SetOffset(0.5, 0), 0.25, white},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tC.tex.ColorAtUV(tC.u, 0.5); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestImageTextureOnObject(t *testing.T) {
+	tex := NewImageTexture(blackWhite()).SetBilinear(false)
+	p := NewPlane()
+
+	testCases := []struct {
+		desc  string
+		point Point
+		want  Color
+	}{
+		{"Left half", NewPoint(0.25, 0, 0.5), black},
+		{"Right half", NewPoint(0.75, 0, 0.5), white},
+		{"Repeats", NewPoint(-0.25, 0, 0.5), white},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tex.ColorAt(p, tC.point); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestLoadImageTexture(t *testing.T) {
+	c := NewCanvas(1, 1)
+	c.SetPixel(0, 0, NewColor(0.5, 0.2, 0.8))
+
+	filename := filepath.Join(t.TempDir(), "texture.png")
+	if err := c.SavePNG(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	srgb, err := LoadImageTexture(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srgb.ColorAtUV(0.5, 0.5); !WithinTolerance(got.R, 0.5, 0.01) || !WithinTolerance(got.B, 0.8, 0.01) {
+		t.Errorf("Got %v, want the linear color back", got)
+	}
+
+	linear, err := LoadImageTextureWithTransfer(filename, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := linear.ColorAtUV(0.5, 0.5).R, SRGB.Encode(0.5); !WithinTolerance(got, want, 0.005) {
+		t.Errorf("Got %v, want the stored value %v", got, want)
+	}
+}
+
+func TestMaterialParameterTextures(t *testing.T) {
+	p := NewPlane()
+	tex := NewImageTexture(blackWhite()).SetBilinear(false)
+
+	m := NewMaterial().
+		SetReflective(0.8).
+		SetShininess(100).
+		SetParameterTexture(ReflectiveParameter, tex).
+		SetParameterTexture(ShininessParameter, tex)
+
+	testCases := []struct {
+		desc      string
+		parameter MaterialParameter
+		point     Point
+		want      float64
+	}{
+		{"Black scales to nothing", ReflectiveParameter, NewPoint(0.25, 0, 0.5), 0},
+		{"White keeps the value", ReflectiveParameter, NewPoint(0.75, 0, 0.5), 0.8},
+		{"Shininess", ShininessParameter, NewPoint(0.75, 0, 0.5), 100},
+		{"Without a texture", DiffuseParameter, NewPoint(0.25, 0, 0.5), 0.9},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := m.ParameterAt(tC.parameter, p, tC.point); !WithinTolerance(got, tC.want, 1e-9) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestLightingUsesParameterTextures(t *testing.T) {
+	p := NewPlane()
+	m := NewMaterial().SetParameterTexture(AmbientParameter, NewImageTexture(blackWhite()).SetBilinear(false))
+	light := NewPointLight(NewPoint(0, 10, 0), white)
+
+	testCases := []struct {
+		desc  string
+		point Point
+		want  Color
+	}{
+		{"No ambient", NewPoint(0.25, 0, 0.5), black},
+		{"Full ambient", NewPoint(0.75, 0, 0.5), NewColor(0.1, 0.1, 0.1)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := m.Lighting(p, light, tC.point, NewVec(0, 1, 0), NewVec(0, 1, 0), true); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestMetalFuzzinessTexture(t *testing.T) {
+	p := NewPlane()
+	p.SetNewMaterial(NewMetal(white, 1).SetFuzzinessTexture(NewImageTexture(blackWhite()).SetBilinear(false)))
+
+	r := NewRay(NewPoint(0.25, 1, 0.5), NewVec(0, -1, 0))
+	i := NewIntersection(1, p)
+	comps := PrepareComputations(i, r)
+
+	var attenuation Color
+	var scattered Ray
+
+	p.GetNewMaterial().Scatter(&r, &comps, &attenuation, &scattered, rand.New(rand.NewSource(1)))
+
+	if want := NewVec(0, 1, 0); !scattered.Direction.Eq(want) {
+		t.Errorf("Got %v, want a mirror reflection %v", scattered.Direction, want)
+	}
+}
@@ -0,0 +1,195 @@
+package raytracer
+
+import (
+	"math"
+)
+
+// Integrator computes the radiance arriving along a ray in a world. Camera
+// uses one to turn primary rays into pixel colors.
+type Integrator interface {
+	ColorAt(w *World, r *Ray, remaining int) Color
+}
+
+// CoverageIntegrator is an Integrator that can tell when a camera ray escapes
+// to the background. ColorAndCoverageAt returns black with a coverage of 0
+// for such a ray and the usual color with a coverage of 1 otherwise, which a
+// transparent Camera turns into alpha.
+type CoverageIntegrator interface {
+	Integrator
+	ColorAndCoverageAt(w *World, r *Ray, remaining int) (Color, float64)
+}
+
+// PathTracer
+
+type PathTracer struct {
+	// MaxIndirect clamps the luminance of light arriving after a bounce,
+	// which trades a little energy for fewer fireflies. Zero disables it.
+	MaxIndirect float64
+}
+
+func NewPathTracer() *PathTracer {
+	return &PathTracer{}
+}
+
+func (pt *PathTracer) SetMaxIndirect(max float64) *PathTracer {
+	pt.MaxIndirect = max
+
+	return pt
+}
+
+// clampIndirect scales a color down so its luminance is at most MaxIndirect
+func (pt *PathTracer) clampIndirect(c Color) Color {
+	if pt.MaxIndirect <= 0 {
+		return c
+	}
+
+	if l := Luminance(c); l > pt.MaxIndirect {
+		return c.MulFloat(pt.MaxIndirect / l)
+	}
+
+	return c
+}
+
+func (pt *PathTracer) ColorAt(w *World, r *Ray, remaining int) Color {
+	color, _ := pt.colorAt(w, r, remaining, true, true)
+
+	return color
+}
+
+// ColorAndCoverageAt is ColorAt for a transparent camera. A path covers the
+// pixel unless the camera ray reaches the background, crossing media it
+// enters without scattering in them or in fog.
+func (pt *PathTracer) ColorAndCoverageAt(w *World, r *Ray, remaining int) (Color, float64) {
+	color, escaped := pt.colorAt(w, r, remaining, true, true)
+
+	if escaped {
+		return colorBlack, 0
+	}
+
+	return color, 1
+}
+
+// colorAt traces a path. After a diffuse bounce the environment has already
+// been sampled directly, so seesEnvironment is false to avoid counting it twice.
+// Only the primary ray from the camera can be hidden from the background, and
+// escaped reports when it reaches it.
+func (pt *PathTracer) colorAt(w *World, r *Ray, remaining int, primary, seesEnvironment bool) (Color, bool) {
+	if remaining <= 0 {
+		return colorBlack, false
+	}
+
+	xs := w.Intersect(*r)
+	hit, didHit := GetHit(xs)
+
+	var comps *Computations
+	distance := math.Inf(1)
+
+	if didHit {
+		comps = PrepareComputationsWithHit(hit, *r, xs)
+		distance = hit.Time * r.Direction.Mag()
+	}
+
+	if medium, start, end := w.mediumAlong(r, comps, distance); medium != nil {
+		direction := r.Direction.Norm()
+		unitRay := NewRay(r.Origin.AddVec(direction.Mul(start)), direction)
+
+		if collision, collides := medium.SampleCollision(unitRay, end-start, w.Source); collides {
+			point := unitRay.Position(collision.Distance)
+			scattered := NewRay(point, medium.SamplePhase(direction, w.Source))
+
+			light, _ := pt.colorAt(w, &scattered, remaining-1, false, true)
+			indirect := pt.clampIndirect(light)
+
+			return collision.Emission.Add(collision.Albedo.Mul(indirect)), false
+		}
+	}
+
+	if !didHit {
+		if primary {
+			return w.CameraMissColor(r), true
+		}
+
+		if _, ok := w.environment(); ok && !seesEnvironment {
+			return colorBlack, false
+		}
+
+		return w.MissColor(r), false
+	}
+
+	// Everything seen at the hit is absorbed by the glass on the way there
+	color, escaped := pt.shade(w, r, comps, remaining, primary, seesEnvironment)
+
+	return Absorption(r, comps).Mul(color), escaped
+}
+
+// shade returns the light leaving a hit back along r, and whether a camera
+// ray continued through the hit to the background
+func (pt *PathTracer) shade(w *World, r *Ray, comps *Computations, remaining int, primary, seesEnvironment bool) (Color, bool) {
+	var scattered Ray
+	var attenuation Color
+
+	object := *comps.Object
+	material := object.GetNewMaterial()
+
+	emit := material.Emit()
+
+	if !material.Scatter(r, comps, &attenuation, &scattered, w.Source) {
+		return emit, false
+	}
+
+	// Crossing into or out of a medium does not change the path
+	if _, ok := material.(Participating); ok {
+		color, escaped := pt.colorAt(w, &scattered, remaining-1, primary, seesEnvironment)
+
+		return attenuation.Mul(color), escaped
+	}
+
+	albedo, lambertian := lambertianAlbedo(material, comps)
+	environment, hasEnvironment := w.environment()
+	sampleEnvironment := lambertian && hasEnvironment
+
+	if sampleEnvironment {
+		emit = emit.Add(pt.sampleEnvironment(w, environment, comps, albedo))
+	}
+
+	light, _ := pt.colorAt(w, &scattered, remaining-1, false, !sampleEnvironment)
+	indirect := pt.clampIndirect(light)
+
+	return emit.Add(attenuation.Mul(indirect)), false
+}
+
+// sampleEnvironment estimates the light from the environment reflected by a
+// Lambertian surface by sampling the environment by luminance
+func (pt *PathTracer) sampleEnvironment(w *World, environment Environment, comps *Computations, albedo Color) Color {
+	direction, pdf := environment.Sample(w.Source)
+	cosTheta := direction.Dot(comps.Normalv)
+
+	if pdf <= 0 || cosTheta <= 0 {
+		return colorBlack
+	}
+
+	transmittance := w.Transmittance(NewRay(comps.OverPoint, direction))
+
+	if transmittance <= 0 {
+		return colorBlack
+	}
+
+	radiance := environment.ColorAt(direction)
+
+	return albedo.Mul(radiance).MulFloat(transmittance * cosTheta / (math.Pi * pdf))
+}
+
+// lambertianAlbedo returns the albedo of materials that scatter as a pure
+// Lambertian surface, which lets integrators light them directly
+func lambertianAlbedo(s Scatters, comps *Computations) (Color, bool) {
+	switch m := s.(type) {
+	case *Diffuse:
+		return m.AlbedoAt(*comps.Object, comps.Point), true
+	case *Material:
+		if m.Reflectivity == 0 && m.Transparency == 0 {
+			return m.ColorAt(*comps.Object, comps.Point), true
+		}
+	}
+
+	return colorBlack, false
+}
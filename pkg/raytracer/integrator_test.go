@@ -0,0 +1,106 @@
+package raytracer
+
+import (
+	"math"
+	"testing"
+)
+
+type constantIntegrator struct {
+	color Color
+}
+
+func (ci constantIntegrator) ColorAt(w *World, r *Ray, remaining int) Color {
+	return ci.color
+}
+
+func TestCameraRendersWithIntegrator(t *testing.T) {
+	w := NewDefaultWorld()
+	want := NewColor(0.25, 0.5, 0.75)
+
+	c := NewCamera(5, 5, math.Pi/2).SetIntegrator(constantIntegrator{want})
+	c.Samples = 1
+
+	canvas := c.Render(w)
+
+	for i, got := range canvas.Pixels {
+		if !got.Eq(want) {
+			t.Fatalf("Pixel %d, got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPathTracerMissReturnsBackground(t *testing.T) {
+	w := NewWorld()
+	background := NewColor(0.5, 0.7, 1.0)
+	w.Background = NewConstantBackground(background)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	got := NewPathTracer().ColorAt(w, &r, 4)
+
+	if !got.Eq(background) {
+		t.Errorf("Got %v, want %v", got, background)
+	}
+}
+
+func TestPathTracerEmissiveHit(t *testing.T) {
+	w := NewWorld()
+	s := NewSphere()
+	s.SetNewMaterial(NewEmissive(NewColor(4, 4, 4)))
+	w.AddObject(s)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	got := NewPathTracer().ColorAt(w, &r, 4)
+	want := NewColor(4, 4, 4)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPathTracerStopsAtMaximumDepth(t *testing.T) {
+	w := NewDefaultWorld()
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	got := NewPathTracer().ColorAt(w, &r, 0)
+
+	if !got.Eq(colorBlack) {
+		t.Errorf("Got %v, want %v", got, colorBlack)
+	}
+}
+
+func TestPathTracerClampsIndirectLight(t *testing.T) {
+	w := NewWorld()
+	w.Background = NewConstantBackground(NewColor(100, 50, 0))
+
+	floor := NewPlane()
+	floor.SetNewMaterial(NewMetal(NewColor(1, 1, 1), 0))
+	w.AddObject(floor)
+
+	r := NewRay(NewPoint(0, 1, -1), NewVec(0, -1, 1))
+
+	testCases := []struct {
+		desc string
+		pt   *PathTracer
+		want Color
+	}{
+		{desc: "Unclamped", pt: NewPathTracer(), want: NewColor(100, 50, 0)},
+		{desc: "Clamped", pt: NewPathTracer().SetMaxIndirect(1), want: NewColor(100, 50, 0).MulFloat(1 / Luminance(NewColor(100, 50, 0)))},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := tC.pt.ColorAt(w, &r, 4)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+
+			// Light seen directly by the camera is never clamped
+			miss := NewRay(NewPoint(0, 1, 0), NewVec(0, 1, 0))
+			if got := tC.pt.ColorAt(w, &miss, 4); !got.Eq(NewColor(100, 50, 0)) {
+				t.Errorf("Got %v, want %v", got, NewColor(100, 50, 0))
+			}
+		})
+	}
+}
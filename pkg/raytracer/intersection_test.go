@@ -11,14 +11,14 @@ type MockShape struct{}
 func (MockShape) Intersect(ray Ray) []Intersection {
 	return []Intersection{}
 }
+func (MockShape) LocalIntersect(ray Ray) []Intersection {
+	return []Intersection{}
+}
 func (MockShape) NormalAt(point Point, i Intersection) Vec {
 	return Vec{}
 }
-func (MockShape) GetMaterial() *Material {
-	return NewMaterial()
-}
-func (MockShape) SetMaterial(*Material) Intersectable {
-	return &MockShape{}
+func (MockShape) LocalNormalAt(point Point, i Intersection) Vec {
+	return Vec{}
 }
 func (MockShape) GetTransform() *Matrix {
 	return NewIdentityMatrix()
@@ -1,6 +1,7 @@
 package raytracer
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 )
@@ -10,9 +11,218 @@ type Scatters interface {
 	Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool
 }
 
+// Material
+type Material struct {
+	Color           Color
+	Pattern         Pattern
+	Texture         Texture
+	Ambient         float64
+	Diffuse         float64
+	Specular        float64
+	Shininess       float64
+	Reflectivity    float64
+	Transparency    float64
+	RefractiveIndex float64
+	// ParameterTextures scale the scalar parameters over the surface
+	ParameterTextures [materialParameterCount]Texture
+}
+
+// MaterialParameter is a scalar parameter of a Material that a texture can
+// vary over a surface
+type MaterialParameter int
+
+const (
+	AmbientParameter MaterialParameter = iota
+	DiffuseParameter
+	SpecularParameter
+	ShininessParameter
+	ReflectiveParameter
+	TransparencyParameter
+	materialParameterCount
+)
+
+func NewMaterial() *Material {
+	return &Material{
+		Color:           NewColor(1, 1, 1),
+		Pattern:         nil,
+		Ambient:         0.1,
+		Diffuse:         0.9,
+		Specular:        0.9,
+		Shininess:       200,
+		Reflectivity:    0,
+		Transparency:    0,
+		RefractiveIndex: 1,
+	}
+}
+
+func (m *Material) SetColor(c Color) *Material {
+	m.Color = c
+
+	return m
+}
+func (m *Material) SetPattern(p Pattern) *Material {
+	m.Pattern = p
+
+	return m
+}
+func (m *Material) SetTexture(t Texture) *Material {
+	m.Texture = t
+
+	return m
+}
+func (m *Material) SetAmbient(a float64) *Material {
+	m.Ambient = a
+
+	return m
+}
+func (m *Material) SetDiffuse(d float64) *Material {
+	m.Diffuse = d
+
+	return m
+}
+func (m *Material) SetSpecular(s float64) *Material {
+	m.Specular = s
+
+	return m
+}
+func (m *Material) SetShininess(s float64) *Material {
+	m.Shininess = s
+
+	return m
+}
+func (m *Material) SetReflective(r float64) *Material {
+	m.Reflectivity = r
+
+	return m
+}
+func (m *Material) SetTransparency(t float64) *Material {
+	m.Transparency = t
+
+	return m
+}
+func (m *Material) SetRefractiveIndex(ri float64) *Material {
+	m.RefractiveIndex = ri
+
+	return m
+}
+
+// SetParameterTexture scales a parameter by the luminance of a texture, so a
+// gray map goes from 0 where it is black to the set value where it is white
+func (m *Material) SetParameterTexture(p MaterialParameter, t Texture) *Material {
+	m.ParameterTextures[p] = t
+
+	return m
+}
+
+// ParameterAt returns the value of a parameter at a point on an object
+func (m *Material) ParameterAt(p MaterialParameter, object Intersectable, worldPoint Point) float64 {
+	var v float64
+
+	switch p {
+	case AmbientParameter:
+		v = m.Ambient
+	case DiffuseParameter:
+		v = m.Diffuse
+	case SpecularParameter:
+		v = m.Specular
+	case ShininessParameter:
+		v = m.Shininess
+	case ReflectiveParameter:
+		v = m.Reflectivity
+	case TransparencyParameter:
+		v = m.Transparency
+	}
+
+	return textureScale(m.ParameterTextures[p], v, object, worldPoint)
+}
+
+// ColorAt returns the color of the material at a point on an object, from
+// the texture if there is one, then the pattern, then the plain color
+func (m *Material) ColorAt(object Intersectable, worldPoint Point) Color {
+	if m.Texture != nil {
+		return m.Texture.ColorAt(object, worldPoint)
+	}
+
+	if m.Pattern != nil {
+		return m.Pattern.ColorAtObject(object, worldPoint)
+	}
+
+	return m.Color
+}
+
+// Lighting shades a point with the Phong reflection model for a single light
+func (m *Material) Lighting(object Intersectable, light Light, point Point, eyev, normalv Vec, inShadow bool) Color {
+	effectiveColor := m.ColorAt(object, point).Mul(light.GetIntensity())
+	lightv := light.GetPosition().Sub(point).Norm()
+
+	ambient := effectiveColor.MulFloat(m.ParameterAt(AmbientParameter, object, point))
+
+	if inShadow {
+		return ambient
+	}
+
+	lightDotNormal := lightv.Dot(normalv)
+
+	if lightDotNormal < 0 {
+		return ambient
+	}
+
+	diffuse := effectiveColor.MulFloat(m.ParameterAt(DiffuseParameter, object, point) * lightDotNormal)
+
+	reflectv := lightv.Neg().Reflect(normalv)
+	reflectDotEye := reflectv.Dot(eyev)
+
+	if reflectDotEye <= 0 {
+		return ambient.Add(diffuse)
+	}
+
+	factor := math.Pow(reflectDotEye, m.ParameterAt(ShininessParameter, object, point))
+	specular := light.GetIntensity().MulFloat(m.ParameterAt(SpecularParameter, object, point) * factor)
+
+	return ambient.Add(diffuse).Add(specular)
+}
+
+func (m *Material) Emit() Color {
+	return NewColor(0, 0, 0)
+}
+
+// Scatter picks a reflected, refracted or diffuse bounce with probabilities
+// given by Reflectivity and Transparency so Phong materials also path trace
+func (m *Material) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool {
+	choice := source.Float64()
+	reflectivity := m.ParameterAt(ReflectiveParameter, *comps.Object, comps.Point)
+
+	if choice < reflectivity {
+		*scattered = NewRay(comps.OverPoint, comps.Reflectv)
+		*attenuation = NewColor(1, 1, 1)
+
+		return true
+	}
+
+	if choice < reflectivity+m.ParameterAt(TransparencyParameter, *comps.Object, comps.Point) {
+		*scattered = scatterDielectric(rayIn, comps, source)
+		*attenuation = NewColor(1, 1, 1)
+
+		return true
+	}
+
+	scatterDirection := comps.Normalv.Add(RandomUnitVector(source))
+
+	if scatterDirection.NearZero() {
+		scatterDirection = comps.Normalv
+	}
+
+	*scattered = NewRay(comps.OverPoint, scatterDirection)
+	*attenuation = m.ColorAt(*comps.Object, comps.Point)
+
+	return true
+}
+
 // Diffuse
 type Diffuse struct {
 	Albedo Color
+	// Texture replaces Albedo when set
+	Texture Texture
 }
 
 func NewDiffuse(color Color) *Diffuse {
@@ -21,6 +231,17 @@ func NewDiffuse(color Color) *Diffuse {
 	}
 }
 
+func (d *Diffuse) SetTexture(t Texture) *Diffuse {
+	d.Texture = t
+
+	return d
+}
+
+// AlbedoAt returns the color of the surface at a point on an object
+func (d *Diffuse) AlbedoAt(object Intersectable, worldPoint Point) Color {
+	return textureColor(d.Texture, d.Albedo, object, worldPoint)
+}
+
 func (d *Diffuse) Emit() Color {
 	return NewColor(0, 0, 0)
 }
@@ -33,7 +254,7 @@ func (d *Diffuse) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, s
 	}
 
 	*scattered = NewRay(comps.OverPoint, scatterDirection)
-	*attenuation = d.Albedo
+	*attenuation = d.AlbedoAt(*comps.Object, comps.Point)
 
 	return true
 }
@@ -41,6 +262,10 @@ func (d *Diffuse) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, s
 type Metal struct {
 	Albedo    Color
 	Fuzziness float64
+	// Texture replaces Albedo when set
+	Texture Texture
+	// FuzzinessTexture scales Fuzziness by its luminance when set
+	FuzzinessTexture Texture
 }
 
 func NewMetal(color Color, fuzziness float64) *Metal {
@@ -50,15 +275,38 @@ func NewMetal(color Color, fuzziness float64) *Metal {
 	}
 }
 
+func (m *Metal) SetTexture(t Texture) *Metal {
+	m.Texture = t
+
+	return m
+}
+
+func (m *Metal) SetFuzzinessTexture(t Texture) *Metal {
+	m.FuzzinessTexture = t
+
+	return m
+}
+
+// FuzzinessAt returns the fuzziness of the surface at a point on an object
+func (m *Metal) FuzzinessAt(object Intersectable, worldPoint Point) float64 {
+	return textureScale(m.FuzzinessTexture, m.Fuzziness, object, worldPoint)
+}
+
+// AlbedoAt returns the color of the surface at a point on an object
+func (m *Metal) AlbedoAt(object Intersectable, worldPoint Point) Color {
+	return textureColor(m.Texture, m.Albedo, object, worldPoint)
+}
+
 func (m *Metal) Emit() Color {
 	return NewColor(0, 0, 0)
 }
 
 func (m *Metal) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool {
-	scatterDirection := comps.Reflectv.Add(RandomInUnitSphere(source).Mul(m.Fuzziness))
+	fuzziness := m.FuzzinessAt(*comps.Object, comps.Point)
+	scatterDirection := comps.Reflectv.Add(RandomInUnitSphere(source).Mul(fuzziness))
 
 	*scattered = NewRay(comps.OverPoint, scatterDirection)
-	*attenuation = m.Albedo
+	*attenuation = m.AlbedoAt(*comps.Object, comps.Point)
 
 	return true
 }
@@ -66,28 +314,84 @@ func (m *Metal) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, sca
 // Dielectric
 type Dielectric struct {
 	IndexOfRefraction float64
+	Absorption        Color
 }
 
 func NewDielectric(indexOfRefraction float64) *Dielectric {
 	return &Dielectric{
 		IndexOfRefraction: indexOfRefraction,
+		Absorption:        NewColor(0, 0, 0),
 	}
 }
 
+// SetAbsorption sets the Beer-Lambert absorption coefficient per unit of
+// distance traveled inside the medium
+func (d *Dielectric) SetAbsorption(a Color) *Dielectric {
+	d.Absorption = a
+
+	return d
+}
+
+// SetColorAtDistance sets the absorption so that white light has the given
+// color after traveling distance units through the medium. Channels are
+// clamped so the medium neither amplifies light nor absorbs all of it, and a
+// distance that is not positive panics.
+func (d *Dielectric) SetColorAtDistance(c Color, distance float64) *Dielectric {
+	if !(distance > 0) {
+		panic(fmt.Sprintf("Invalid absorption distance %v", distance))
+	}
+
+	d.Absorption = NewColor(
+		-math.Log(clampTransmittance(c.R))/distance,
+		-math.Log(clampTransmittance(c.G))/distance,
+		-math.Log(clampTransmittance(c.B))/distance,
+	)
+
+	return d
+}
+
+// minTransmittance is the darkest a medium can get, which keeps its
+// absorption finite
+const minTransmittance = 1e-6
+
+func clampTransmittance(t float64) float64 {
+	if !(t >= minTransmittance) {
+		return minTransmittance
+	}
+
+	return math.Min(t, 1)
+}
+
+func (d *Dielectric) Transmittance(distance float64) Color {
+	return NewColor(
+		math.Exp(-d.Absorption.R*distance),
+		math.Exp(-d.Absorption.G*distance),
+		math.Exp(-d.Absorption.B*distance),
+	)
+}
+
 func (d *Dielectric) Emit() Color {
 	return NewColor(0, 0, 0)
 }
 
 func (d *Dielectric) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool {
+	// Absorption inside the medium is applied by the integrator with Absorption
 	*attenuation = NewColor(1, 1, 1)
+	*scattered = scatterDielectric(rayIn, comps, source)
+
+	return true
+}
 
-	var refractionRatio float64
-	if !comps.Inside {
-		refractionRatio = 1.0 / d.IndexOfRefraction
-	} else {
-		refractionRatio = d.IndexOfRefraction
+// scatterDielectric reflects or refracts between the media on either side of
+// the hit, as found by PrepareComputationsWithHit
+func scatterDielectric(rayIn *Ray, comps *Computations, source *rand.Rand) Ray {
+	// Touching media with the same index form no optical boundary
+	if comps.N1 == comps.N2 {
+		return NewRay(comps.UnderPoint, rayIn.Direction)
 	}
 
+	refractionRatio := comps.N1 / comps.N2
+
 	unitDirection := rayIn.Direction.Norm()
 
 	cosTheta := math.Min(unitDirection.Mul(-1).Dot(comps.Normalv), 1.0)
@@ -95,17 +399,11 @@ func (d *Dielectric) Scatter(rayIn *Ray, comps *Computations, attenuation *Color
 
 	cannotRefract := refractionRatio*sinTheta > 1.0
 
-	var direction Vec
-
 	if cannotRefract || Reflectance(cosTheta, refractionRatio) > source.Float64() {
-		direction = Reflect(unitDirection, comps.Normalv)
-	} else {
-		direction = Refract(unitDirection, comps.Normalv, refractionRatio)
+		return NewRay(comps.OverPoint, Reflect(unitDirection, comps.Normalv))
 	}
 
-	*scattered = NewRay(comps.UnderPoint, direction)
-
-	return true
+	return NewRay(comps.UnderPoint, Refract(unitDirection, comps.Normalv, refractionRatio))
 }
 
 // Emissive
@@ -127,6 +425,50 @@ func (e *Emissive) Scatter(rayIn *Ray, comps *Computations, attenuation *Color,
 	return false
 }
 
+// RefractiveIndex returns the index of refraction of the medium inside an
+// object with the given material. Opaque materials count as air.
+func RefractiveIndex(s Scatters) float64 {
+	switch m := s.(type) {
+	case *Material:
+		return m.RefractiveIndex
+	case *Dielectric:
+		return m.IndexOfRefraction
+	default:
+		return 1.0
+	}
+}
+
+// Absorption is the Beer-Lambert transmittance of the dielectric a ray
+// travels through to reach a hit, whatever surface it hits there
+func Absorption(rayIn *Ray, comps *Computations) Color {
+	d, ok := comps.Enclosing.(*Dielectric)
+	if !ok {
+		return NewColor(1, 1, 1)
+	}
+
+	return d.Transmittance(comps.Time * rayIn.Direction.Mag())
+}
+
+// Albedo returns the base color of a material at a point on an object
+func Albedo(s Scatters, object Intersectable, worldPoint Point) Color {
+	switch m := s.(type) {
+	case *Material:
+		return m.ColorAt(object, worldPoint)
+	case *Diffuse:
+		return m.AlbedoAt(object, worldPoint)
+	case *Metal:
+		return m.AlbedoAt(object, worldPoint)
+	case *Dielectric:
+		return NewColor(1, 1, 1)
+	case *Emissive:
+		return m.Emission
+	case *Medium:
+		return m.Albedo
+	default:
+		return NewColor(0, 0, 0)
+	}
+}
+
 func Reflectance(cosine, refIdx float64) float64 {
 	r0 := (1 - refIdx) / (1 + refIdx)
 	r0 = r0 * r0
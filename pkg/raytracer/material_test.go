@@ -1,7 +1,9 @@
 package raytracer
 
 import (
+	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"testing"
 )
@@ -175,28 +177,30 @@ func TestTransparencyAndRefractiveIndex(t *testing.T) {
 func TestNewGlassSphere(t *testing.T) {
 	s := NewGlassSphere()
 
-	if !s.Transform.Eq(NewIdentityMatrix()) {
+	if !s.GetTransform().Eq(NewIdentityMatrix()) {
 		t.Error("Invalid default transform")
 	}
 
-	if s.Material.Transparency != 1.0 {
-		t.Error("Invalid transparency")
+	m, ok := s.GetNewMaterial().(*Dielectric)
+
+	if !ok {
+		t.Fatal("Invalid material")
 	}
 
-	if s.Material.RefractiveIndex != 1.5 {
+	if m.IndexOfRefraction != 1.5 {
 		t.Error("Invalid refractive index")
 	}
 }
 
 func TestFindingN1AndN2(t *testing.T) {
 	a := NewGlassSphere().SetTransform(NewScaling(2, 2, 2))
-	a.GetMaterial().SetRefractiveIndex(1.5)
+	a.GetNewMaterial().(*Dielectric).IndexOfRefraction = 1.5
 
 	b := NewGlassSphere().SetTransform(NewTranslation(0, 0, -0.25))
-	b.GetMaterial().SetRefractiveIndex(2.0)
+	b.GetNewMaterial().(*Dielectric).IndexOfRefraction = 2.0
 
 	c := NewGlassSphere().SetTransform(NewTranslation(0, 0, 0.25))
-	c.GetMaterial().SetRefractiveIndex(2.5)
+	c.GetNewMaterial().(*Dielectric).IndexOfRefraction = 2.5
 
 	r := NewRay(NewPoint(0, 0, -4), NewVec(0, 0, 1))
 
@@ -261,3 +265,166 @@ func TestFindingN1AndN2(t *testing.T) {
 		})
 	}
 }
+
+func TestDielectricBetweenMatchingMediaDoesNotBend(t *testing.T) {
+	glass := NewSphere().SetTransform(NewScaling(2, 2, 2))
+	glass.(*object).SetNewMaterial(NewDielectric(1.5))
+
+	inner := NewSphere()
+	d := NewDielectric(1.5)
+	inner.SetNewMaterial(d)
+
+	r := NewRay(NewPoint(0, -0.5, -4), NewVec(0, 0.2, 1).Norm())
+	xs := append(glass.Intersect(r), inner.Intersect(r)...)
+	sort.Sort(IntersectonSorter(xs))
+
+	if *xs[1].Object != Intersectable(inner) {
+		t.Fatal("Expected second intersection with inner sphere")
+	}
+
+	comps := PrepareComputationsWithHit(xs[1], r, xs)
+
+	var attenuation Color
+	var scattered Ray
+
+	d.Scatter(&r, comps, &attenuation, &scattered, NewWorld().Source)
+
+	if !scattered.Direction.Eq(r.Direction) {
+		t.Errorf("Got %v, want %v", scattered.Direction, r.Direction)
+	}
+}
+
+func TestDielectricDefaultAbsorption(t *testing.T) {
+	d := NewDielectric(1.5)
+
+	if !d.Transmittance(100).Eq(NewColor(1, 1, 1)) {
+		t.Errorf("Got %v, want %v", d.Transmittance(100), NewColor(1, 1, 1))
+	}
+}
+
+func TestDielectricColorAtDistance(t *testing.T) {
+	d := NewDielectric(1.5).SetColorAtDistance(NewColor(0.8, 0.5, 0.2), 2)
+
+	testCases := []struct {
+		distance float64
+		want     Color
+	}{
+		{0, NewColor(1, 1, 1)},
+		{2, NewColor(0.8, 0.5, 0.2)},
+		{4, NewColor(0.64, 0.25, 0.04)},
+	}
+	for _, tC := range testCases {
+		t.Run(fmt.Sprint(tC.distance), func(t *testing.T) {
+			got := d.Transmittance(tC.distance)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestDielectricColorAtDistanceClamps(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		color Color
+		want  Color
+	}{
+		{"Black absorbs almost everything", NewColor(0, 0.5, 1), NewColor(minTransmittance, 0.5, 1)},
+		{"Brighter than white does not amplify", NewColor(2, 0.5, 1), NewColor(1, 0.5, 1)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := NewDielectric(1.5).SetColorAtDistance(tC.color, 2).Transmittance(2)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestDielectricColorAtDistanceRejectsDistance(t *testing.T) {
+	for _, distance := range []float64{0, -1, math.NaN()} {
+		t.Run(fmt.Sprint(distance), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+
+			NewDielectric(1.5).SetColorAtDistance(NewColor(0.5, 0.5, 0.5), distance)
+		})
+	}
+}
+
+func TestDielectricAbsorbsAlongPathInside(t *testing.T) {
+	glass := NewSphere()
+	glass.SetTransform(NewScaling(2, 2, 2))
+	glass.SetNewMaterial(NewDielectric(1.5).SetAbsorption(NewColor(0.5, 0, 1)))
+
+	water := NewSphere()
+	water.SetNewMaterial(NewDielectric(1.33))
+
+	diffuse := NewSphere()
+	diffuse.SetNewMaterial(NewDiffuse(white))
+
+	testCases := []struct {
+		desc    string
+		objects []Intersectable
+		ray     Ray
+		hit     int
+		want    Color
+	}{
+		{
+			desc:    "Entering",
+			objects: []Intersectable{glass},
+			ray:     NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1)),
+			hit:     0,
+			want:    NewColor(1, 1, 1),
+		},
+		{
+			desc:    "Exiting",
+			objects: []Intersectable{glass},
+			ray:     NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1)),
+			hit:     1,
+			want:    NewColor(math.Exp(-1), 1, math.Exp(-2)),
+		},
+		{
+			desc:    "Entering water inside the glass",
+			objects: []Intersectable{glass, water},
+			ray:     NewRay(NewPoint(0, 0, -2), NewVec(0, 0, 1)),
+			hit:     1,
+			want:    NewColor(math.Exp(-0.5), 1, math.Exp(-1)),
+		},
+		{
+			desc:    "Leaving water inside the glass",
+			objects: []Intersectable{glass, water},
+			ray:     NewRay(NewPoint(0, 0, -1), NewVec(0, 0, 1)),
+			hit:     2,
+			want:    NewColor(1, 1, 1),
+		},
+		{
+			desc:    "Hitting an opaque object inside the glass",
+			objects: []Intersectable{glass, diffuse},
+			ray:     NewRay(NewPoint(0, 0, -2), NewVec(0, 0, 1)),
+			hit:     1,
+			want:    NewColor(math.Exp(-0.5), 1, math.Exp(-1)),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := NewWorld()
+			for _, o := range tC.objects {
+				w.AddObject(o)
+			}
+
+			xs := w.Intersect(tC.ray)
+			comps := PrepareComputationsWithHit(xs[tC.hit], tC.ray, xs)
+
+			if got := Absorption(&tC.ray, comps); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
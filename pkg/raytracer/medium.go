@@ -0,0 +1,232 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Participating is a material that fills the inside of its closed object
+// with a medium rays scatter in. Rays passed to it have unit direction and
+// distances are in world units.
+type Participating interface {
+	Scatters
+	// SampleCollision follows r through the medium and reports where it
+	// first collides before maxDistance
+	SampleCollision(r Ray, maxDistance float64, source *rand.Rand) (MediumCollision, bool)
+	// TransmittanceAlong is the fraction of light passing distance along r
+	TransmittanceAlong(r Ray, distance float64, source *rand.Rand) float64
+	SamplePhase(direction Vec, source *rand.Rand) Vec
+}
+
+// MediumCollision is where a ray scatters in a medium. Emission is the
+// radiance the medium emits there and Albedo scales the scattered light.
+type MediumCollision struct {
+	Distance float64
+	Albedo   Color
+	Emission Color
+}
+
+// Medium is a participating medium like fog or smoke with constant density.
+// Used as the material of a closed object it fills the inside of that object,
+// and as World.Fog it fills a region outside other objects. Rays travel an
+// exponentially distributed distance through it before they scatter.
+type Medium struct {
+	Density    float64
+	Albedo     Color
+	Anisotropy float64
+}
+
+// NewMedium returns an isotropic medium. Density is the chance per unit
+// distance that a ray scatters and albedo is the color it scatters with.
+func NewMedium(density float64, albedo Color) *Medium {
+	return &Medium{
+		Density: density,
+		Albedo:  albedo,
+	}
+}
+
+// SetAnisotropy sets the Henyey-Greenstein g, where positive values scatter
+// forward, negative values backward and 0 is isotropic
+func (m *Medium) SetAnisotropy(g float64) *Medium {
+	m.Anisotropy = g
+
+	return m
+}
+
+func (m *Medium) Emit() Color {
+	return colorBlack
+}
+
+// Scatter lets rays cross the boundary of the medium unchanged, scattering
+// inside it is done by the integrator with SampleDistance
+func (m *Medium) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool {
+	*attenuation = NewColor(1, 1, 1)
+	*scattered = NewRay(comps.UnderPoint, rayIn.Direction)
+
+	return true
+}
+
+// SampleDistance picks how far a ray travels through the medium before it
+// scatters. It returns false when the ray gets further than maxDistance.
+func (m *Medium) SampleDistance(maxDistance float64, source *rand.Rand) (float64, bool) {
+	if m.Density <= 0 {
+		return 0, false
+	}
+
+	distance := -math.Log(1-source.Float64()) / m.Density
+
+	return distance, distance < maxDistance
+}
+
+func (m *Medium) SampleCollision(r Ray, maxDistance float64, source *rand.Rand) (MediumCollision, bool) {
+	distance, collides := m.SampleDistance(maxDistance, source)
+
+	return MediumCollision{Distance: distance, Albedo: m.Albedo}, collides
+}
+
+// Transmittance is the fraction of light that passes distance through the
+// medium without scattering
+func (m *Medium) Transmittance(distance float64) float64 {
+	return math.Exp(-m.Density * distance)
+}
+
+func (m *Medium) TransmittanceAlong(r Ray, distance float64, source *rand.Rand) float64 {
+	return m.Transmittance(distance)
+}
+
+// SamplePhase picks a new direction for a ray travelling in direction from
+// the Henyey-Greenstein phase function
+func (m *Medium) SamplePhase(direction Vec, source *rand.Rand) Vec {
+	g := m.Anisotropy
+	u := source.Float64()
+
+	var cosTheta float64
+	if math.Abs(g) < 1e-3 {
+		cosTheta = 1 - 2*u
+	} else {
+		s := (1 - g*g) / (1 - g + 2*g*u)
+		cosTheta = (1 + g*g - s*s) / (2 * g)
+	}
+
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * source.Float64()
+
+	w := direction.Norm()
+	a, b := orthonormalBasis(w)
+
+	return a.Mul(math.Cos(phi) * sinTheta).Add(b.Mul(math.Sin(phi) * sinTheta)).Add(w.Mul(cosTheta))
+}
+
+// Phase is the Henyey-Greenstein density per unit solid angle of scattering
+// by an angle with the given cosine
+func (m *Medium) Phase(cosTheta float64) float64 {
+	g := m.Anisotropy
+	denominator := 1 + g*g - 2*g*cosTheta
+
+	return (1 - g*g) / (4 * math.Pi * denominator * math.Sqrt(denominator))
+}
+
+// mediumAlong returns the medium a ray passes through on its way to a hit
+// distance away, and where along the ray it is in it. Rays outside every
+// object are in the world fog while they are inside its bounds.
+func (w *World) mediumAlong(r *Ray, comps *Computations, distance float64) (Participating, float64, float64) {
+	if comps == nil || comps.Enclosing == nil {
+		start, end := w.fogSpan(r, 0, distance)
+
+		if w.Fog == nil || start >= end {
+			return nil, 0, 0
+		}
+
+		return w.Fog, start, end
+	}
+
+	m, _ := comps.Enclosing.(Participating)
+
+	return m, 0, distance
+}
+
+// fogSpan clips the part of a ray from distance from to distance to against
+// the fog bounds. Distances are in world units along the ray.
+func (w *World) fogSpan(r *Ray, from, to float64) (float64, float64) {
+	if w.FogBounds == nil {
+		return 0, 0
+	}
+
+	unitRay := NewRay(r.Origin, r.Direction.Norm())
+	tmin, tmax := w.FogBounds.IntersectRange(unitRay)
+
+	return math.Max(from, tmin), math.Min(to, tmax)
+}
+
+// fogTransmittance is the fraction of light that passes through the fog
+// between two distances along a ray
+func (w *World) fogTransmittance(r *Ray, from, to float64) float64 {
+	if w.Fog == nil {
+		return 1
+	}
+
+	start, end := w.fogSpan(r, math.Max(from, 0), to)
+	if start >= end {
+		return 1
+	}
+
+	return w.Fog.Transmittance(end - start)
+}
+
+// Transmittance returns the fraction of light that travels along a shadow
+// ray to infinity. Opaque objects block it and media attenuate it, which is
+// estimated with ratio tracking for media that are not constant. The fog
+// counts only where the ray is outside every medium.
+func (w *World) Transmittance(r Ray) float64 {
+	xs := w.Intersect(r)
+	distanceScale := r.Direction.Mag()
+	direction := r.Direction.Norm()
+
+	// Media are entered and left by object, as for refraction containers
+	entered := make(map[Intersectable]float64)
+	transmittance := 1.0
+	outside := 0.0
+
+	for _, item := range xs {
+		m, isMedium := (*item.Object).GetNewMaterial().(Participating)
+
+		if !isMedium {
+			if item.Time >= 0 {
+				return 0
+			}
+
+			continue
+		}
+
+		solid := solidOf(*item.Object)
+		start, inside := entered[solid]
+
+		if !inside {
+			if len(entered) == 0 {
+				transmittance *= w.fogTransmittance(&r, outside, item.Time*distanceScale)
+			}
+
+			entered[solid] = item.Time
+
+			continue
+		}
+
+		delete(entered, solid)
+
+		if len(entered) == 0 {
+			outside = item.Time * distanceScale
+		}
+
+		if item.Time > 0 {
+			start = math.Max(start, 0)
+			segment := NewRay(r.Position(start), direction)
+			transmittance *= m.TransmittanceAlong(segment, (item.Time-start)*distanceScale, w.Source)
+		}
+	}
+
+	if len(entered) == 0 {
+		transmittance *= w.fogTransmittance(&r, outside, math.Inf(1))
+	}
+
+	return transmittance
+}
@@ -0,0 +1,303 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMediumSampleDistance(t *testing.T) {
+	m := NewMedium(2, NewColor(1, 1, 1))
+	source := rand.New(rand.NewSource(1))
+
+	var sum float64
+	n := 20000
+
+	for i := 0; i < n; i++ {
+		d, scatters := m.SampleDistance(math.Inf(1), source)
+
+		if !scatters {
+			t.Fatal("Ray escaped an infinite medium")
+		}
+
+		sum += d
+	}
+
+	got := sum / float64(n)
+
+	if math.Abs(got-0.5) > 0.02 {
+		t.Errorf("Got mean distance %v, want %v", got, 0.5)
+	}
+}
+
+func TestMediumPhaseFunction(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		anisotropy float64
+	}{
+		{desc: "Isotropic", anisotropy: 0},
+		{desc: "Forward", anisotropy: 0.7},
+		{desc: "Backward", anisotropy: -0.4},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m := NewMedium(1, NewColor(1, 1, 1)).SetAnisotropy(tC.anisotropy)
+			source := rand.New(rand.NewSource(1))
+			direction := NewVec(0, 0, 1)
+
+			var sum float64
+			n := 20000
+
+			for i := 0; i < n; i++ {
+				sum += m.SamplePhase(direction, source).Dot(direction)
+			}
+
+			got := sum / float64(n)
+
+			if math.Abs(got-tC.anisotropy) > 0.02 {
+				t.Errorf("Got mean cosine %v, want %v", got, tC.anisotropy)
+			}
+
+			// The phase function integrates to one over the sphere
+			var integral float64
+			steps := 10000
+			for i := 0; i < steps; i++ {
+				cosTheta := -1 + 2*(float64(i)+0.5)/float64(steps)
+				integral += m.Phase(cosTheta) * 2 * math.Pi * 2 / float64(steps)
+			}
+
+			if math.Abs(integral-1) > 1e-3 {
+				t.Errorf("Got integral %v, want %v", integral, 1)
+			}
+		})
+	}
+}
+
+func TestComputationsEnclosingMedium(t *testing.T) {
+	smoke := NewMedium(1, NewColor(1, 1, 1))
+
+	boundary := NewSphere()
+	boundary.SetTransform(NewScaling(2, 2, 2))
+	boundary.SetNewMaterial(smoke)
+
+	inner := NewSphere()
+	inner.SetTransform(NewScaling(0.5, 0.5, 0.5))
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+	xs := []Intersection{
+		NewIntersection(3, boundary),
+		NewIntersection(4.5, inner),
+		NewIntersection(5.5, inner),
+		NewIntersection(7, boundary),
+	}
+
+	testCases := []struct {
+		desc  string
+		index int
+		want  Scatters
+	}{
+		{desc: "Entering the medium", index: 0, want: nil},
+		{desc: "Hitting an object inside", index: 1, want: smoke},
+		{desc: "Inside the object", index: 2, want: inner.GetNewMaterial()},
+		{desc: "Leaving the medium", index: 3, want: smoke},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			comps := PrepareComputationsWithHit(xs[tC.index], r, xs)
+
+			if comps.Enclosing != tC.want {
+				t.Errorf("Got %v, want %v", comps.Enclosing, tC.want)
+			}
+		})
+	}
+}
+
+func TestWorldTransmittanceThroughMedium(t *testing.T) {
+	w := NewWorld()
+
+	smoke := NewSphere()
+	smoke.SetNewMaterial(NewMedium(0.5, NewColor(1, 1, 1)))
+	w.AddObject(smoke)
+
+	testCases := []struct {
+		desc string
+		ray  Ray
+		want float64
+	}{
+		{
+			desc: "Through the whole medium",
+			ray:  NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1)),
+			want: math.Exp(-1),
+		},
+		{
+			desc: "From the center",
+			ray:  NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1)),
+			want: math.Exp(-0.5),
+		},
+		{
+			desc: "Missing the medium",
+			ray:  NewRay(NewPoint(0, 5, -5), NewVec(0, 0, 1)),
+			want: 1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := w.Transmittance(tC.ray)
+
+			if math.Abs(got-tC.want) > 1e-6 {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+
+	w.AddObject(NewSphere().SetTransform(NewTranslation(0, 0, 3)))
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+	if got := w.Transmittance(r); got != 0 {
+		t.Errorf("Got %v, want %v behind an opaque object", got, 0)
+	}
+}
+
+func TestPathTracerWhiteMediumConservesEnergy(t *testing.T) {
+	w := NewWorld()
+	w.Source = rand.New(rand.NewSource(1))
+	w.Background = NewConstantBackground(NewColor(1, 1, 1))
+
+	smoke := NewSphere()
+	smoke.SetNewMaterial(NewMedium(3, NewColor(1, 1, 1)))
+	w.AddObject(smoke)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	var sum Color
+	n := 500
+	for i := 0; i < n; i++ {
+		sum = sum.Add(NewPathTracer().ColorAt(w, &r, 100))
+	}
+	got := sum.MulFloat(1 / float64(n))
+
+	if math.Abs(got.R-1) > 0.02 {
+		t.Errorf("Got %v, want %v", got, NewColor(1, 1, 1))
+	}
+}
+
+func TestPathTracerFogAttenuatesLight(t *testing.T) {
+	w := NewWorld()
+	w.Source = rand.New(rand.NewSource(1))
+	w.SetFog(NewMedium(0.1, NewColor(0, 0, 0)), fogBox(100))
+
+	wall := NewPlane()
+	wall.SetTransform(NewTranslation(0, 0, 10).Mul(NewRotationX(math.Pi / 2)))
+	wall.SetNewMaterial(NewEmissive(NewColor(1, 1, 1)))
+	w.AddObject(wall)
+
+	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
+
+	var sum Color
+	n := 4000
+	for i := 0; i < n; i++ {
+		sum = sum.Add(NewPathTracer().ColorAt(w, &r, 4))
+	}
+	got := sum.MulFloat(1 / float64(n))
+
+	if math.Abs(got.R-math.Exp(-1)) > 0.03 {
+		t.Errorf("Got %v, want %v", got.R, math.Exp(-1))
+	}
+}
+
+// fogBox is a cube of fog centered at the origin
+func fogBox(halfSize float64) *BoundingBox {
+	return NewBoundingBoxWithValues(NewPoint(-halfSize, -halfSize, -halfSize), NewPoint(halfSize, halfSize, halfSize))
+}
+
+func TestPathTracerFogEndsAtItsBounds(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		fog    *Medium
+		origin Point
+		want   float64
+	}{
+		// Scattered light still reaches the background
+		{"Thin white fog", NewMedium(0.001, NewColor(1, 1, 1)), NewPoint(0, 0, 0), 1},
+		// Only the light that crosses the 10 units of fog unscattered arrives
+		{"Black fog", NewMedium(0.1, NewColor(0, 0, 0)), NewPoint(0, 0, 0), math.Exp(-1)},
+		// The fog stays where it is when the ray starts outside it
+		{"Black fog ahead", NewMedium(0.05, NewColor(0, 0, 0)), NewPoint(0, 0, -50), math.Exp(-1)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := NewWorld()
+			w.Source = rand.New(rand.NewSource(1))
+			w.Background = NewConstantBackground(NewColor(1, 1, 1))
+			w.SetFog(tC.fog, fogBox(10))
+
+			r := NewRay(tC.origin, NewVec(0, 0, 1))
+
+			var sum Color
+			n := 4000
+			for i := 0; i < n; i++ {
+				sum = sum.Add(NewPathTracer().ColorAt(w, &r, 4))
+			}
+			got := sum.MulFloat(1 / float64(n))
+
+			if math.Abs(got.R-tC.want) > 0.03 {
+				t.Errorf("Got %v, want %v", got.R, tC.want)
+			}
+		})
+	}
+}
+
+func TestWorldTransmittanceThroughFog(t *testing.T) {
+	smoke := NewSphere()
+	smoke.SetTransform(NewTranslation(0, 5, 0))
+	smoke.SetNewMaterial(NewMedium(0, NewColor(1, 1, 1)))
+
+	testCases := []struct {
+		desc    string
+		objects []Intersectable
+		ray     Ray
+		want    float64
+	}{
+		{"From the center", nil, NewRay(NewPoint(0, 0, 0), NewVec(0, 1, 0)), math.Exp(-1)},
+		{"From a bounce near the edge", nil, NewRay(NewPoint(0, 8, 0), NewVec(0, 2, 0)), math.Exp(-0.2)},
+		{"From outside the fog", nil, NewRay(NewPoint(0, 20, 0), NewVec(0, 1, 0)), 1},
+		{"Across the whole fog", nil, NewRay(NewPoint(0, -20, 0), NewVec(0, 1, 0)), math.Exp(-2)},
+		{"Through a clear medium", []Intersectable{smoke}, NewRay(NewPoint(0, 0, 0), NewVec(0, 1, 0)), math.Exp(-0.8)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w := NewWorld()
+			w.SetFog(NewMedium(0.1, NewColor(1, 1, 1)), fogBox(10))
+			for _, o := range tC.objects {
+				w.AddObject(o)
+			}
+
+			if got := w.Transmittance(tC.ray); math.Abs(got-tC.want) > 1e-9 {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestWorldTransmittanceThroughOverlappingMedia(t *testing.T) {
+	smoke := NewMedium(0.1, NewColor(1, 1, 1))
+
+	a := NewSphere()
+	a.SetTransform(NewTranslation(0, 1, 0))
+	a.SetNewMaterial(smoke)
+
+	b := NewSphere()
+	b.SetTransform(NewTranslation(0, 2, 0))
+	b.SetNewMaterial(smoke)
+
+	w := NewWorld()
+	w.AddObject(a)
+	w.AddObject(b)
+
+	r := NewRay(NewPoint(0, -5, 0), NewVec(0, 1, 0))
+
+	// Each sphere is crossed over its full diameter, the overlap counts twice
+	if got, want := w.Transmittance(r), math.Exp(-0.4); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package raytracer
+
+import "math"
+
+// Noise is Ken Perlin's improved gradient noise. It is smooth, repeats every
+// 256 units, is 0 at every integer point and stays within [-1, 1].
+func Noise(p Point) float64 {
+	xf, yf, zf := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+
+	X, Y, Z := int(xf)&255, int(yf)&255, int(zf)&255
+	x, y, z := p.X-xf, p.Y-yf, p.Z-zf
+
+	u, v, w := fade(x), fade(y), fade(z)
+
+	A := perlinPermutation[X] + Y
+	AA := perlinPermutation[A] + Z
+	AB := perlinPermutation[A+1] + Z
+	B := perlinPermutation[X+1] + Y
+	BA := perlinPermutation[B] + Z
+	BB := perlinPermutation[B+1] + Z
+
+	return lerp(
+		lerp(
+			lerp(grad(perlinPermutation[AA], x, y, z), grad(perlinPermutation[BA], x-1, y, z), u),
+			lerp(grad(perlinPermutation[AB], x, y-1, z), grad(perlinPermutation[BB], x-1, y-1, z), u),
+			v),
+		lerp(
+			lerp(grad(perlinPermutation[AA+1], x, y, z-1), grad(perlinPermutation[BA+1], x-1, y, z-1), u),
+			lerp(grad(perlinPermutation[AB+1], x, y-1, z-1), grad(perlinPermutation[BB+1], x-1, y-1, z-1), u),
+			v),
+		w)
+}
+
+// FractalNoise sums octaves of noise, each at twice the frequency and half
+// the amplitude of the one before. The result is scaled back to [-1, 1].
+func FractalNoise(p Point, octaves int) float64 {
+	return sumOctaves(p, octaves, Noise)
+}
+
+// Turbulence is FractalNoise of the absolute value of noise, which folds the
+// noise into sharp creases. It stays within [0, 1].
+func Turbulence(p Point, octaves int) float64 {
+	return sumOctaves(p, octaves, func(p Point) float64 {
+		return math.Abs(Noise(p))
+	})
+}
+
+func sumOctaves(p Point, octaves int, f func(Point) float64) float64 {
+	var sum, total float64
+	amplitude := 1.0
+
+	for i := 0; i < octaves; i++ {
+		sum += amplitude * f(p)
+		total += amplitude
+
+		amplitude /= 2
+		p = NewPoint(p.X*2, p.Y*2, p.Z*2)
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return sum / total
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// grad picks one of the 12 gradients pointing to the edges of a cube from
+// the low bits of a hash and dots it with the offset
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+
+	u := y
+	if h < 8 {
+		u = x
+	}
+
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+
+	return u + v
+}
+
+// perlinPermutation is Perlin's reference permutation, repeated so lookups
+// never have to wrap
+var perlinPermutation = func() [512]int {
+	p := [256]int{
+		151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225,
+		140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23, 190, 6, 148,
+		247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32,
+		57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175,
+		74, 165, 71, 134, 139, 48, 27, 166, 77, 146, 158, 231, 83, 111, 229, 122,
+		60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54,
+		65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169,
+		200, 196, 135, 130, 116, 188, 159, 86, 164, 100, 109, 198, 173, 186, 3, 64,
+		52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126, 255, 82, 85, 212,
+		207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213,
+		119, 248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9,
+		129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104,
+		218, 246, 97, 228, 251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241,
+		81, 51, 145, 235, 249, 14, 239, 107, 49, 192, 214, 31, 181, 199, 106, 157,
+		184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236, 205, 93,
+		222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
+	}
+
+	var doubled [512]int
+	for i := range doubled {
+		doubled[i] = p[i&255]
+	}
+
+	return doubled
+}()
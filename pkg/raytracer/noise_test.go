@@ -0,0 +1,78 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		point Point
+		want  float64
+	}{
+		{"Origin", NewPoint(0, 0, 0), 0},
+		{"Integer point", NewPoint(3, -7, 12), 0},
+		{"Reference value", NewPoint(3.14, 42, 7), 0.13691995878400012},
+		{"Repeats every 256 units", NewPoint(3.14+256, 42, 7-256), 0.13691995878400012},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := Noise(tC.point); !WithinTolerance(got, tC.want, 1e-9) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestNoiseRanges(t *testing.T) {
+	source := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		p := NewPoint(source.Float64()*100-50, source.Float64()*100-50, source.Float64()*100-50)
+
+		if n := Noise(p); n < -1 || n > 1 {
+			t.Fatalf("Got noise %v at %v, want it within [-1, 1]", n, p)
+		}
+
+		if n := FractalNoise(p, 4); n < -1 || n > 1 {
+			t.Fatalf("Got fractal noise %v at %v, want it within [-1, 1]", n, p)
+		}
+
+		if n := Turbulence(p, 4); n < 0 || n > 1 {
+			t.Fatalf("Got turbulence %v at %v, want it within [0, 1]", n, p)
+		}
+	}
+}
+
+func TestNoiseIsSmooth(t *testing.T) {
+	p := NewPoint(1.3, 2.7, -0.4)
+	q := NewPoint(1.3+1e-6, 2.7, -0.4)
+
+	if d := math.Abs(Noise(p) - Noise(q)); d > 1e-5 {
+		t.Errorf("Got a jump of %v between close points, want a smooth change", d)
+	}
+}
+
+func TestFractalNoiseOctaves(t *testing.T) {
+	p := NewPoint(3.14, 42, 7)
+
+	testCases := []struct {
+		desc string
+		got  float64
+		want float64
+	}{
+		{"One octave is plain noise", FractalNoise(p, 1), Noise(p)},
+		{"Two octaves", FractalNoise(p, 2), (Noise(p) + Noise(NewPoint(6.28, 84, 14))/2) / 1.5},
+		{"One octave of turbulence", Turbulence(p, 1), math.Abs(Noise(p))},
+		{"No octaves", FractalNoise(p, 0), 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if !WithinTolerance(tC.got, tC.want, 1e-12) {
+				t.Errorf("Got %v, want %v", tC.got, tC.want)
+			}
+		})
+	}
+}
@@ -5,13 +5,17 @@ type object struct {
 	parent       Intersectable
 	material     Scatters
 	parentObject Intersectable
+	uvMapping    UVMapping
 }
 
+// newObject starts objects with the default Phong material. It scatters like
+// a white Diffuse in the path tracer and shades like the book's default
+// material under Whitted, so scenes look the same with either integrator.
 func newObject() object {
 	return object{
 		transform:    NewIdentityMatrix(),
 		parent:       nil,
-		material:     NewDiffuse(NewColor(1, 1, 1)),
+		material:     NewMaterial(),
 		parentObject: nil,
 	}
 }
@@ -93,3 +97,14 @@ func (o *object) SetNewMaterial(s Scatters) Intersectable {
 
 	return o
 }
+
+// GetUVMapping returns the mapping set on the object, or nil when it uses
+// the natural mapping of its shape
+func (o *object) GetUVMapping() UVMapping {
+	return o.uvMapping
+}
+func (o *object) SetUVMapping(m UVMapping) Intersectable {
+	o.uvMapping = m
+
+	return o
+}
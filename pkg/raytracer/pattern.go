@@ -11,6 +11,12 @@ type Pattern interface {
 	GetTransform() *Matrix
 }
 
+// patternPoint converts a world point to the space of a pattern on an
+// object, through every group the object is in
+func patternPoint(p Pattern, object Intersectable, worldPoint Point) Point {
+	return p.GetTransform().Inverse().MulPoint(object.WorldToObject(worldPoint))
+}
+
 // StripePattern
 
 type StripePattern struct {
@@ -39,10 +45,7 @@ func (sp *StripePattern) ColorAt(p Point) Color {
 	}
 }
 func (sp *StripePattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
-	objectPoint := object.GetTransform().Inverse().MulPoint(worldPoint)
-	patternPoint := sp.GetTransform().Inverse().MulPoint(objectPoint)
-
-	return sp.ColorAt(patternPoint)
+	return sp.ColorAt(patternPoint(sp, object, worldPoint))
 }
 
 // GradientPattern
@@ -72,10 +75,7 @@ func (gp *GradientPattern) ColorAt(p Point) Color {
 	return gp.A.Add(distance.MulFloat(fraction))
 }
 func (gp *GradientPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
-	objectPoint := object.GetTransform().Inverse().MulPoint(worldPoint)
-	patternPoint := gp.GetTransform().Inverse().MulPoint(objectPoint)
-
-	return gp.ColorAt(patternPoint)
+	return gp.ColorAt(patternPoint(gp, object, worldPoint))
 }
 
 // RingPattern
@@ -106,10 +106,7 @@ func (rp *RingPattern) ColorAt(p Point) Color {
 	return rp.B
 }
 func (rp *RingPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
-	objectPoint := object.GetTransform().Inverse().MulPoint(worldPoint)
-	patternPoint := rp.GetTransform().Inverse().MulPoint(objectPoint)
-
-	return rp.ColorAt(patternPoint)
+	return rp.ColorAt(patternPoint(rp, object, worldPoint))
 }
 
 // CheckerPattern
@@ -140,10 +137,7 @@ func (cp *CheckerPattern) ColorAt(p Point) Color {
 	return cp.B
 }
 func (cp *CheckerPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
-	objectPoint := object.GetTransform().Inverse().MulPoint(worldPoint)
-	patternPoint := cp.GetTransform().Inverse().MulPoint(objectPoint)
-
-	return cp.ColorAt(patternPoint)
+	return cp.ColorAt(patternPoint(cp, object, worldPoint))
 }
 
 // TestPattern
@@ -168,8 +162,176 @@ func (cp *TestPattern) ColorAt(p Point) Color {
 	return NewColor(p.X, p.Y, p.Z)
 }
 func (cp *TestPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
-	objectPoint := object.GetTransform().Inverse().MulPoint(worldPoint)
-	patternPoint := cp.GetTransform().Inverse().MulPoint(objectPoint)
+	return cp.ColorAt(patternPoint(cp, object, worldPoint))
+}
+
+// NoisePattern blends between two colors with fractal noise
+
+type NoisePattern struct {
+	A         Color
+	B         Color
+	Octaves   int
+	Transform *Matrix
+}
+
+func NewNoisePattern(a, b Color) *NoisePattern {
+	return &NoisePattern{a, b, 1, NewIdentityMatrix()}
+}
+
+func (np *NoisePattern) SetOctaves(o int) *NoisePattern {
+	np.Octaves = o
+
+	return np
+}
+
+func (np *NoisePattern) GetTransform() *Matrix {
+	return np.Transform
+}
+func (np *NoisePattern) SetTransform(m *Matrix) Pattern {
+	np.Transform = m
+
+	return np
+}
+func (np *NoisePattern) ColorAt(p Point) Color {
+	return mix(np.A, np.B, (FractalNoise(p, np.Octaves)+1)/2)
+}
+func (np *NoisePattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
+	return np.ColorAt(patternPoint(np, object, worldPoint))
+}
+
+// MarblePattern is bands along x, one unit wide, bent by turbulence. A is the
+// color in the middle of a band and B at its edges. Turbulence sets how far
+// the bands are pushed around.
+
+type MarblePattern struct {
+	A          Color
+	B          Color
+	Turbulence float64
+	Octaves    int
+	Transform  *Matrix
+}
+
+func NewMarblePattern(a, b Color) *MarblePattern {
+	return &MarblePattern{a, b, 5, 4, NewIdentityMatrix()}
+}
+
+func (mp *MarblePattern) SetTurbulence(t float64) *MarblePattern {
+	mp.Turbulence = t
+
+	return mp
+}
+func (mp *MarblePattern) SetOctaves(o int) *MarblePattern {
+	mp.Octaves = o
+
+	return mp
+}
+
+func (mp *MarblePattern) GetTransform() *Matrix {
+	return mp.Transform
+}
+func (mp *MarblePattern) SetTransform(m *Matrix) Pattern {
+	mp.Transform = m
+
+	return mp
+}
+func (mp *MarblePattern) ColorAt(p Point) Color {
+	phase := math.Pi * (p.X + mp.Turbulence*Turbulence(p, mp.Octaves))
+
+	return mix(mp.B, mp.A, math.Abs(math.Sin(phase)))
+}
+func (mp *MarblePattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
+	return mp.ColorAt(patternPoint(mp, object, worldPoint))
+}
+
+// WoodPattern is rings around the y axis, one unit apart like RingPattern,
+// made irregular by turbulence. Each ring fades from A on the inside to B on
+// the outside.
+
+type WoodPattern struct {
+	A          Color
+	B          Color
+	Turbulence float64
+	Octaves    int
+	Transform  *Matrix
+}
+
+func NewWoodPattern(a, b Color) *WoodPattern {
+	return &WoodPattern{a, b, 0.2, 3, NewIdentityMatrix()}
+}
+
+func (wp *WoodPattern) SetTurbulence(t float64) *WoodPattern {
+	wp.Turbulence = t
+
+	return wp
+}
+func (wp *WoodPattern) SetOctaves(o int) *WoodPattern {
+	wp.Octaves = o
+
+	return wp
+}
+
+func (wp *WoodPattern) GetTransform() *Matrix {
+	return wp.Transform
+}
+func (wp *WoodPattern) SetTransform(m *Matrix) Pattern {
+	wp.Transform = m
+
+	return wp
+}
+func (wp *WoodPattern) ColorAt(p Point) Color {
+	distance := math.Sqrt(p.X*p.X+p.Z*p.Z) + wp.Turbulence*Turbulence(p, wp.Octaves)
+
+	return mix(wp.A, wp.B, distance-math.Floor(distance))
+}
+func (wp *WoodPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
+	return wp.ColorAt(patternPoint(wp, object, worldPoint))
+}
+
+// PerturbedPattern moves the points another pattern is evaluated at by up to
+// Scale units with noise, which makes hard edges wavy. The other pattern
+// keeps its own transform inside this one.
+
+type PerturbedPattern struct {
+	Pattern   Pattern
+	Scale     float64
+	Octaves   int
+	Transform *Matrix
+}
+
+func NewPerturbedPattern(p Pattern, scale float64) *PerturbedPattern {
+	return &PerturbedPattern{p, scale, 1, NewIdentityMatrix()}
+}
+
+func (pp *PerturbedPattern) SetOctaves(o int) *PerturbedPattern {
+	pp.Octaves = o
+
+	return pp
+}
+
+func (pp *PerturbedPattern) GetTransform() *Matrix {
+	return pp.Transform
+}
+func (pp *PerturbedPattern) SetTransform(m *Matrix) Pattern {
+	pp.Transform = m
+
+	return pp
+}
+func (pp *PerturbedPattern) ColorAt(p Point) Color {
+	// Offsetting the lookups by whole units keeps the axes from moving
+	// together and leaves integer points where they are
+	jittered := NewPoint(
+		p.X+pp.Scale*FractalNoise(p, pp.Octaves),
+		p.Y+pp.Scale*FractalNoise(NewPoint(p.X+31, p.Y, p.Z), pp.Octaves),
+		p.Z+pp.Scale*FractalNoise(NewPoint(p.X, p.Y+67, p.Z), pp.Octaves),
+	)
+
+	return pp.Pattern.ColorAt(pp.Pattern.GetTransform().Inverse().MulPoint(jittered))
+}
+func (pp *PerturbedPattern) ColorAtObject(object Intersectable, worldPoint Point) Color {
+	return pp.ColorAt(patternPoint(pp, object, worldPoint))
+}
 
-	return cp.ColorAt(patternPoint)
+// mix blends from a at t = 0 to b at t = 1
+func mix(a, b Color, t float64) Color {
+	return a.Add(b.Sub(a).MulFloat(t))
 }
@@ -1,6 +1,7 @@
 package raytracer
 
 import (
+	"math"
 	"testing"
 )
 
@@ -205,7 +206,6 @@ func TestCheckerPatternRepeatZ(t *testing.T) {
 func TestTestPatternWithObjectTransformation(t *testing.T) {
 	s := NewSphere().SetTransform(NewScaling(2, 2, 2))
 	p := NewTestPattern()
-	s.GetMaterial().SetPattern(p)
 
 	c := p.ColorAtObject(s, NewPoint(2, 3, 4))
 
@@ -217,7 +217,6 @@ func TestTestPatternWithObjectTransformation(t *testing.T) {
 func TestTestPatternWithPatternTransformation(t *testing.T) {
 	s := NewSphere()
 	p := NewTestPattern().SetTransform(NewScaling(2, 2, 2))
-	s.GetMaterial().SetPattern(p)
 
 	c := p.ColorAtObject(s, NewPoint(2, 3, 4))
 
@@ -229,7 +228,6 @@ func TestTestPatternWithPatternTransformation(t *testing.T) {
 func TestTestPatternWithObjectAndPatternTransformation(t *testing.T) {
 	s := NewSphere().SetTransform(NewScaling(2, 2, 2))
 	p := NewTestPattern().SetTransform(NewTranslation(0.5, 1, 1.5))
-	s.GetMaterial().SetPattern(p)
 
 	c := p.ColorAtObject(s, NewPoint(2.5, 3, 3.5))
 
@@ -237,3 +235,76 @@ func TestTestPatternWithObjectAndPatternTransformation(t *testing.T) {
 		t.Errorf("Got %v, want %v", c, NewColor(0.75, 0.5, 0.25))
 	}
 }
+
+func TestNoisePatterns(t *testing.T) {
+	gray := NewColor(0.5, 0.5, 0.5)
+
+	testCases := []struct {
+		desc    string
+		pattern Pattern
+		point   Point
+		want    Color
+	}{
+		{"Noise is halfway at integer points", NewNoisePattern(black, white).SetOctaves(3), NewPoint(1, 2, 3), gray},
+		{"Marble band edge", NewMarblePattern(white, black).SetTurbulence(0), NewPoint(1, 0.3, 0.7), black},
+		{"Marble band middle", NewMarblePattern(white, black).SetTurbulence(0), NewPoint(1.5, 0.3, 0.7), white},
+		{"Wood ring start", NewWoodPattern(white, black).SetTurbulence(0), NewPoint(0.6, 0.2, 0.8), white},
+		{"Wood halfway through a ring", NewWoodPattern(white, black).SetTurbulence(0), NewPoint(1.5, 0.2, 0), gray},
+		{"Perturbing by nothing", NewPerturbedPattern(NewStripePattern(white, black).SetTransform(NewScaling(0.5, 1, 1)), 0), NewPoint(0.7, 0.3, 0.1), black},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tC.pattern.ColorAt(tC.point); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestMarblePatternWithTurbulence(t *testing.T) {
+	p := NewMarblePattern(white, black)
+
+	for _, point := range []Point{NewPoint(0.3, 0.1, 0.2), NewPoint(-4.2, 1.7, 3.3), NewPoint(12.9, -0.4, 8.1)} {
+		got := p.ColorAt(point)
+
+		if got.R < 0 || got.R > 1 || got.R != got.G || got.G != got.B {
+			t.Errorf("Got %v at %v, want a blend of white and black", got, point)
+		}
+	}
+}
+
+func TestPerturbedPatternMovesPoints(t *testing.T) {
+	p := NewPerturbedPattern(NewTestPattern(), 0.2).SetOctaves(2)
+
+	moved := false
+
+	for _, point := range []Point{NewPoint(0.3, 0.1, 0.2), NewPoint(-4.2, 1.7, 3.3), NewPoint(12.9, -0.4, 8.1)} {
+		c := p.ColorAt(point)
+		offset := NewPoint(c.R, c.G, c.B).Sub(point)
+
+		if math.Abs(offset.X) > 0.2 || math.Abs(offset.Y) > 0.2 || math.Abs(offset.Z) > 0.2 {
+			t.Errorf("Got %v moved by %v, want at most 0.2 on each axis", point, offset)
+		}
+
+		if offset.Mag() > 0 {
+			moved = true
+		}
+	}
+
+	if !moved {
+		t.Error("Got every point unmoved, want them jittered")
+	}
+}
+
+func TestPerturbedPatternWithObjectTransformation(t *testing.T) {
+	s := NewSphere()
+	s.SetTransform(NewScaling(2, 2, 2))
+
+	p := NewPerturbedPattern(NewTestPattern(), 0.5)
+	p.SetTransform(NewTranslation(0.5, 1, 1.5))
+
+	// Noise is 0 at integer points, so nothing moves there
+	if got, want := p.ColorAtObject(s, NewPoint(3, 4, 5)), NewColor(1, 1, 1); !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
@@ -47,6 +47,10 @@ func (p *Plane) LocalNormalAt(Point, Intersection) Vec {
 	return NewVec(0, 1, 0)
 }
 
+func (p *Plane) LocalUVAt(objectPoint Point) (u, v float64) {
+	return PlanarMap(objectPoint)
+}
+
 func (pl *Plane) WorldToObject(p Point) Point {
 	parent := pl.GetParent()
 
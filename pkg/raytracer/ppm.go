@@ -0,0 +1,365 @@
+package raytracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ppmMaxLineLength keeps plain PPM lines within what the format allows
+const ppmMaxLineLength = 70
+
+// GetPPMString encodes the canvas as a plain PPM with sRGB values
+func (c *Canvas) GetPPMString() string {
+	return c.GetPPMStringWithOutput(NewOutput())
+}
+
+// GetPPMStringWithOutput encodes the canvas as a plain PPM after exposing,
+// tone mapping and encoding it with output
+func (c *Canvas) GetPPMStringWithOutput(output *Output) string {
+	var sb strings.Builder
+
+	// Writing to a strings.Builder never fails
+	c.WritePPM(&sb, output)
+
+	return sb.String()
+}
+
+// SavePPM writes the canvas as a plain P3 PPM with sRGB values
+func (c *Canvas) SavePPM(filename string) error {
+	return saveCanvas(filename, func(w io.Writer) error {
+		return c.WritePPM(w, NewOutput())
+	})
+}
+
+// SavePPMBinary writes the canvas as a binary P6 PPM with sRGB values
+func (c *Canvas) SavePPMBinary(filename string) error {
+	return saveCanvas(filename, func(w io.Writer) error {
+		return c.WritePPMBinary(w, NewOutput())
+	})
+}
+
+// SavePFM writes the canvas as a PFM file, keeping the full linear range of
+// the colors
+func (c *Canvas) SavePFM(filename string) error {
+	return saveCanvas(filename, c.WritePFM)
+}
+
+// WritePPM writes the canvas as a plain P3 PPM, with no line longer than 70
+// characters
+func (c *Canvas) WritePPM(w io.Writer, output *Output) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "P3\n%d %d\n255\n", c.Width, c.Height)
+
+	for y := 0; y < c.Height; y++ {
+		lineLength := 0
+
+		for x := 0; x < c.Width; x++ {
+			pixel := output.Encode(c.GetPixel(x, y))
+
+			for _, v := range [3]float64{pixel.R, pixel.G, pixel.B} {
+				value := strconv.Itoa(getColorValue(v))
+
+				if lineLength > 0 && lineLength+len(value) >= ppmMaxLineLength {
+					bw.WriteByte('\n')
+					lineLength = 0
+				}
+
+				if lineLength > 0 {
+					bw.WriteByte(' ')
+					lineLength++
+				}
+
+				bw.WriteString(value)
+				lineLength += len(value)
+			}
+		}
+
+		bw.WriteByte('\n')
+	}
+
+	return bw.Flush()
+}
+
+// WritePPMBinary writes the canvas as a binary P6 PPM with 8 bits per
+// channel
+func (c *Canvas) WritePPMBinary(w io.Writer, output *Output) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "P6\n%d %d\n255\n", c.Width, c.Height)
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			rgba := output.RGBA(c.GetPixel(x, y))
+
+			bw.Write([]byte{rgba.R, rgba.G, rgba.B})
+		}
+	}
+
+	return bw.Flush()
+}
+
+// WritePFM writes the canvas as a little endian color PFM. PFM stores rows
+// from the bottom up.
+func (c *Canvas) WritePFM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	// A negative scale marks little endian values
+	fmt.Fprintf(bw, "PF\n%d %d\n-1.0\n", c.Width, c.Height)
+
+	row := make([]float32, c.Width*3)
+
+	for y := c.Height - 1; y >= 0; y-- {
+		for x := 0; x < c.Width; x++ {
+			p := c.GetPixel(x, y)
+			row[x*3], row[x*3+1], row[x*3+2] = float32(p.R), float32(p.G), float32(p.B)
+		}
+
+		if err := binary.Write(bw, binary.LittleEndian, row); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// LoadPPM reads a P3 or P6 PPM file with sRGB values into a linear canvas
+func LoadPPM(filename string) (*Canvas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadPPM(f)
+}
+
+// ReadPPM reads a P3 or P6 PPM image with sRGB values into a linear canvas
+func ReadPPM(r io.Reader) (*Canvas, error) {
+	return ReadPPMWithTransfer(r, SRGB)
+}
+
+// ReadPPMWithTransfer reads a P3 or P6 PPM image, decoding its values with
+// a transfer function. Binary files with a maximum value above 255 use two
+// big endian bytes per value.
+func ReadPPMWithTransfer(r io.Reader, transfer TransferFunction) (*Canvas, error) {
+	pr := &pnmReader{r: bufio.NewReader(r)}
+
+	magic := pr.token()
+	if magic != "P3" && magic != "P6" {
+		return nil, errors.New("not a P3 or P6 PPM file")
+	}
+
+	width, height := pr.int(), pr.int()
+	maxValue := pr.int()
+
+	if pr.err != nil {
+		return nil, fmt.Errorf("reading PPM header: %w", pr.err)
+	}
+
+	if _, err := imagePixels("PPM", width, height); err != nil {
+		return nil, err
+	}
+
+	if maxValue <= 0 || maxValue > 0xFFFF {
+		return nil, fmt.Errorf("invalid PPM maximum value %d", maxValue)
+	}
+
+	canvas := NewCanvas(width, height)
+
+	var value func() int
+
+	if magic == "P3" {
+		value = pr.int
+	} else {
+		// A single whitespace byte separates the header from the data
+		pr.r.ReadByte()
+
+		buf := make([]byte, 2)
+		size := 1
+		if maxValue > 0xFF {
+			size = 2
+		}
+
+		value = func() int {
+			if pr.err != nil {
+				return 0
+			}
+
+			if _, err := io.ReadFull(pr.r, buf[:size]); err != nil {
+				pr.err = err
+				return 0
+			}
+
+			if size == 1 {
+				return int(buf[0])
+			}
+
+			return int(binary.BigEndian.Uint16(buf))
+		}
+	}
+
+	decode := func(v int) float64 {
+		return transfer.Decode(math.Min(float64(v)/float64(maxValue), 1))
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b := value(), value(), value()
+
+			canvas.SetPixel(x, y, NewColor(decode(r), decode(g), decode(b)))
+		}
+	}
+
+	if pr.err != nil {
+		return nil, fmt.Errorf("reading PPM pixels: %w", pr.err)
+	}
+
+	return canvas, nil
+}
+
+// LoadPFM reads a PFM file into a canvas
+func LoadPFM(filename string) (*Canvas, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadPFM(f)
+}
+
+// ReadPFM reads a color (PF) or grayscale (Pf) PFM image in either byte
+// order. Grayscale values are copied into all three channels.
+func ReadPFM(r io.Reader) (*Canvas, error) {
+	pr := &pnmReader{r: bufio.NewReader(r)}
+
+	magic := pr.token()
+	if magic != "PF" && magic != "Pf" {
+		return nil, errors.New("not a PFM file")
+	}
+
+	width, height := pr.int(), pr.int()
+	scale := pr.float()
+
+	if pr.err != nil {
+		return nil, fmt.Errorf("reading PFM header: %w", pr.err)
+	}
+
+	if _, err := imagePixels("PFM", width, height); err != nil {
+		return nil, err
+	}
+
+	if scale == 0 {
+		return nil, errors.New("invalid PFM scale 0")
+	}
+
+	pr.r.ReadByte()
+
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	channels := 3
+	if magic == "Pf" {
+		channels = 1
+	}
+
+	canvas := NewCanvas(width, height)
+	row := make([]float32, width*channels)
+
+	for y := height - 1; y >= 0; y-- {
+		if err := binary.Read(pr.r, order, row); err != nil {
+			return nil, fmt.Errorf("reading PFM pixels: %w", err)
+		}
+
+		for x := 0; x < width; x++ {
+			if channels == 1 {
+				v := float64(row[x])
+				canvas.SetPixel(x, y, NewColor(v, v, v))
+			} else {
+				canvas.SetPixel(x, y, NewColor(float64(row[x*3]), float64(row[x*3+1]), float64(row[x*3+2])))
+			}
+		}
+	}
+
+	return canvas, nil
+}
+
+// pnmReader reads the whitespace separated header tokens shared by PPM and
+// PFM files, skipping comments, and remembers the first error
+type pnmReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (p *pnmReader) token() string {
+	if p.err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	for {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			if sb.Len() == 0 {
+				p.err = err
+			}
+
+			return sb.String()
+		}
+
+		switch {
+		case b == '#' && sb.Len() == 0:
+			if _, err := p.r.ReadString('\n'); err != nil {
+				p.err = err
+				return ""
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if sb.Len() > 0 {
+				// Leave the whitespace after the token for binary data
+				p.r.UnreadByte()
+				return sb.String()
+			}
+		default:
+			sb.WriteByte(b)
+		}
+	}
+}
+
+func (p *pnmReader) int() int {
+	token := p.token()
+	if p.err != nil {
+		return 0
+	}
+
+	v, err := strconv.Atoi(token)
+	if err != nil {
+		p.err = err
+	}
+
+	return v
+}
+
+func (p *pnmReader) float() float64 {
+	token := p.token()
+	if p.err != nil {
+		return 0
+	}
+
+	v, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		p.err = err
+	}
+
+	return v
+}
@@ -0,0 +1,278 @@
+package raytracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func ppmTestCanvas() *Canvas {
+	c := NewCanvas(30, 4)
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			c.SetPixel(x, y, NewColor(float64(x)/29, float64(y)/3, 0.5))
+		}
+	}
+
+	return c
+}
+
+func TestWritePPMMatchesString(t *testing.T) {
+	c := ppmTestCanvas()
+
+	var buf bytes.Buffer
+	if err := c.WritePPM(&buf, NewOutput()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := buf.String(), c.GetPPMString(); got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) > ppmMaxLineLength {
+			t.Errorf("Got line of %v characters, want at most %v", len(line), ppmMaxLineLength)
+		}
+	}
+}
+
+func TestWritePPMBinary(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.SetPixel(0, 0, NewColor(1, 0, 0.5))
+	c.SetPixel(1, 0, NewColor(0, 1.5, 0))
+
+	var buf bytes.Buffer
+	if err := c.WritePPMBinary(&buf, NewOutput().SetTransfer(Linear)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]byte("P6\n2 1\n255\n"), 255, 0, 128, 0, 255, 0)
+
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPPMRoundTrip(t *testing.T) {
+	c := ppmTestCanvas()
+
+	testCases := []struct {
+		desc  string
+		write func(*bytes.Buffer) error
+	}{
+		{"P3", func(b *bytes.Buffer) error { return c.WritePPM(b, NewOutput()) }},
+		{"P6", func(b *bytes.Buffer) error { return c.WritePPMBinary(b, NewOutput()) }},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tC.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadPPM(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Width != c.Width || got.Height != c.Height {
+				t.Fatalf("Got %dx%d, want %dx%d", got.Width, got.Height, c.Width, c.Height)
+			}
+
+			// 8 bits of sRGB are accurate to about half a percent
+			for i, want := range c.Pixels {
+				if !colorsClose(got.Pixels[i], want, 0.01) {
+					t.Fatalf("Got %v at %d, want %v", got.Pixels[i], i, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPPM(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data string
+		want []Color
+	}{
+		{
+			"Plain with comments",
+			"P3\n# a comment\n2 1 # size\n10\n10 0 5\n0 10 0\n",
+			[]Color{NewColor(1, 0, 0.5), NewColor(0, 1, 0)},
+		},
+		{
+			"Binary",
+			"P6 2 1 255\n\xff\x00\x80\x00\xff\x00",
+			[]Color{NewColor(1, 0, 128.0/255), NewColor(0, 1, 0)},
+		},
+		{
+			"Binary 16-bit",
+			"P6\n1 1\n65535\n\xff\xff\x00\x00\x80\x00",
+			[]Color{NewColor(1, 0, 32768.0/65535)},
+		},
+		{
+			// Whitespace bytes are valid values in binary data
+			"Binary data starting with whitespace",
+			"P6\n1 1\n255\n\x0a\x20\x09",
+			[]Color{NewColor(10.0/255, 32.0/255, 9.0/255)},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := ReadPPMWithTransfer(strings.NewReader(tC.data), Linear)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tC.want {
+				if !got.Pixels[i].Eq(want) {
+					t.Errorf("Got %v, want %v", got.Pixels[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPPMErrors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data string
+	}{
+		{"Wrong magic", "P5\n1 1\n255\n\x00"},
+		{"Missing size", "P3\n"},
+		{"Bad maximum", "P3\n1 1\n0\n0 0 0"},
+		{"Truncated plain", "P3\n2 1\n255\n1 2 3"},
+		{"Truncated binary", "P6\n2 1\n255\n\x01\x02\x03"},
+		{"Too large plain", "P3\n100000 100000\n255\n0 0 0"},
+		{"Too large binary", "P6\n100000 100000\n255\n\x00\x00\x00"},
+		{"Overflowing size", "P6\n4294967297 4294967297\n255\n\x00\x00\x00"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := ReadPPM(strings.NewReader(tC.data)); err == nil {
+				t.Errorf("Got no error, want one")
+			}
+		})
+	}
+}
+
+func TestPFMRoundTrip(t *testing.T) {
+	c := hdrTestCanvas(5, 3)
+	c.SetPixel(0, 0, NewColor(-1, 1e-6, 65504))
+
+	var buf bytes.Buffer
+	if err := c.WritePFM(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "PF\n5 3\n-1.0\n") {
+		t.Errorf("Got header %q, want PF 5 3 -1.0", buf.String()[:12])
+	}
+
+	got, err := ReadPFM(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range c.Pixels {
+		want := NewColor(float64(float32(p.R)), float64(float32(p.G)), float64(float32(p.B)))
+
+		if got.Pixels[i] != want {
+			t.Errorf("Got %v at %d, want %v", got.Pixels[i], i, want)
+		}
+	}
+}
+
+func TestReadPFMErrors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data string
+	}{
+		{"Wrong magic", "P6\n1 1\n-1.0\n"},
+		{"Empty", "PF\n0 1\n-1.0\n"},
+		{"Zero scale", "PF\n1 1\n0\n"},
+		{"Too large", "PF\n100000 100000\n-1.0\n"},
+		{"Overflowing size", "Pf\n4294967297 4294967297\n-1.0\n"},
+		{"Truncated", "PF\n1 1\n-1.0\n\x00\x00"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := ReadPFM(strings.NewReader(tC.data)); err == nil {
+				t.Errorf("Got no error, want one")
+			}
+		})
+	}
+}
+
+func TestReadPFMBigEndianGrayscale(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("Pf\n2 2\n1.0\n")
+
+	// Rows are stored bottom up
+	binary.Write(&buf, binary.BigEndian, []float32{3, 4, 1, 2})
+
+	got, err := ReadPFM(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range []float64{1, 2, 3, 4} {
+		if want := NewColor(v, v, v); got.Pixels[i] != want {
+			t.Errorf("Got %v at %d, want %v", got.Pixels[i], i, want)
+		}
+	}
+}
+
+func TestLoadCanvasReadsPPMAndPFM(t *testing.T) {
+	c := NewCanvas(2, 2)
+	c.SetPixel(1, 0, NewColor(0.5, 2, 0))
+
+	dir := t.TempDir()
+
+	testCases := []struct {
+		desc      string
+		filename  string
+		save      func(string) error
+		tolerance float64
+	}{
+		{"P3", "a.ppm", c.SavePPM, 0.01},
+		{"P6", "b.ppm", c.SavePPMBinary, 0.01},
+		{"PFM", "c.pfm", c.SavePFM, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			filename := filepath.Join(dir, tC.filename)
+
+			if err := tC.save(filename); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadCanvas(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := c.GetPixel(1, 0)
+			if tC.tolerance > 0 {
+				// 8-bit files clamp to white
+				want.G = math.Min(want.G, 1)
+			}
+
+			if p := got.GetPixel(1, 0); !colorsClose(p, want, tC.tolerance) {
+				t.Errorf("Got %v, want %v", p, want)
+			}
+		})
+	}
+}
+
+func TestSavePPMReturnsErrors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "missing", "out.ppm")
+
+	if err := NewCanvas(1, 1).SavePPM(filename); err == nil {
+		t.Errorf("Got no error, want one")
+	}
+}
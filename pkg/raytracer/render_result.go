@@ -0,0 +1,191 @@
+package raytracer
+
+import (
+	"fmt"
+)
+
+// RenderResult holds the beauty render together with auxiliary buffers taken
+// from the first hit of a primary ray through each pixel center. Pixels where
+// the ray misses everything are zero in every auxiliary buffer. Variance is
+// the variance of each beauty pixel over its samples.
+type RenderResult struct {
+	Beauty     *Canvas
+	Depth      *Canvas
+	Normal     *Canvas
+	Position   *Canvas
+	Albedo     *Canvas
+	ObjectID   *Canvas
+	MaterialID *Canvas
+	Variance   *Canvas
+}
+
+func NewRenderResult(width, height int) *RenderResult {
+	return &RenderResult{
+		Beauty:     NewCanvas(width, height),
+		Depth:      NewCanvas(width, height),
+		Normal:     NewCanvas(width, height),
+		Position:   NewCanvas(width, height),
+		Albedo:     NewCanvas(width, height),
+		ObjectID:   NewCanvas(width, height),
+		MaterialID: NewCanvas(width, height),
+		Variance:   NewCanvas(width, height),
+	}
+}
+
+// AOVSample is the auxiliary data for a single pixel
+type AOVSample struct {
+	Depth      float64
+	Normal     Vec
+	Position   Point
+	Albedo     Color
+	ObjectID   int
+	MaterialID int
+	Variance   Color
+}
+
+func (rr *RenderResult) SetPixel(x, y int, beauty Color, s AOVSample) {
+	rr.Beauty.SetPixel(x, y, beauty)
+	rr.Depth.SetPixel(x, y, NewColor(s.Depth, s.Depth, s.Depth))
+	rr.Normal.SetPixel(x, y, NewColor(s.Normal.X, s.Normal.Y, s.Normal.Z))
+	rr.Position.SetPixel(x, y, NewColor(s.Position.X, s.Position.Y, s.Position.Z))
+	rr.Albedo.SetPixel(x, y, s.Albedo)
+
+	objectID := float64(s.ObjectID)
+	rr.ObjectID.SetPixel(x, y, NewColor(objectID, objectID, objectID))
+
+	materialID := float64(s.MaterialID)
+	rr.MaterialID.SetPixel(x, y, NewColor(materialID, materialID, materialID))
+
+	rr.Variance.SetPixel(x, y, s.Variance)
+}
+
+// Buffers returns every buffer keyed by its name
+func (rr *RenderResult) Buffers() map[string]*Canvas {
+	return map[string]*Canvas{
+		"beauty":     rr.Beauty,
+		"depth":      rr.Depth,
+		"normal":     rr.Normal,
+		"position":   rr.Position,
+		"albedo":     rr.Albedo,
+		"objectid":   rr.ObjectID,
+		"materialid": rr.MaterialID,
+		"variance":   rr.Variance,
+	}
+}
+
+// SavePNGs writes every buffer as <basename>-<buffer>.png. Buffers that are
+// not colors are remapped so they are viewable as 8-bit images and are
+// written without sRGB encoding. It stops at the first buffer that fails to
+// save and returns its error.
+func (rr *RenderResult) SavePNGs(basename string) error {
+	data := NewOutput().SetTransfer(Linear)
+
+	buffers := []struct {
+		name   string
+		canvas *Canvas
+		output *Output
+	}{
+		{"beauty", rr.Beauty, NewOutput()},
+		{"albedo", rr.Albedo, NewOutput()},
+		{"depth", rr.Depth.Normalized(), data},
+		{"position", rr.Position.Normalized(), data},
+		{"normal", rr.Normal.Remap(func(c Color) Color {
+			return NewColor(c.R*0.5+0.5, c.G*0.5+0.5, c.B*0.5+0.5)
+		}), data},
+		{"objectid", rr.ObjectID.Remap(idColor), data},
+		{"materialid", rr.MaterialID.Remap(idColor), data},
+		{"variance", rr.Variance.Normalized(), data},
+	}
+
+	for _, b := range buffers {
+		if err := b.canvas.SavePNGWithOutput(fmt.Sprintf("%s-%s.png", basename, b.name), b.output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// idColor maps an ID stored in a canvas to a distinct color, with 0 as black
+func idColor(c Color) Color {
+	id := uint32(c.R)
+
+	if id == 0 {
+		return colorBlack
+	}
+
+	h := id * 2654435761
+
+	return NewColor(
+		float64((h>>16)&0xFF)/255,
+		float64((h>>8)&0xFF)/255,
+		float64(h&0xFF)/255,
+	)
+}
+
+// sceneIDs numbers the leaf objects and materials of a world in depth first
+// order, starting at 1. Worlds built the same way get the same IDs.
+type sceneIDs struct {
+	objects   map[Intersectable]int
+	materials map[Scatters]int
+}
+
+func newSceneIDs(w *World) *sceneIDs {
+	ids := &sceneIDs{
+		objects:   make(map[Intersectable]int),
+		materials: make(map[Scatters]int),
+	}
+
+	for _, object := range w.Objects {
+		ids.add(*object)
+	}
+
+	return ids
+}
+
+func (ids *sceneIDs) add(i Intersectable) {
+	switch i := i.(type) {
+	case *Group:
+		for _, child := range i.Items {
+			ids.add(child)
+		}
+	case *CSG:
+		ids.add(i.Left)
+		ids.add(i.Right)
+	case *object:
+		ids.add(i.parentObject)
+	default:
+		if _, ok := ids.objects[i]; !ok {
+			ids.objects[i] = len(ids.objects) + 1
+		}
+
+		material := i.GetNewMaterial()
+
+		if _, ok := ids.materials[material]; !ok {
+			ids.materials[material] = len(ids.materials) + 1
+		}
+	}
+}
+
+// aovSample fills an AOVSample from the first hit along a primary ray
+func aovSample(w *World, r Ray, ids *sceneIDs) AOVSample {
+	xs := w.Intersect(r)
+	hit, didHit := GetHit(xs)
+
+	if !didHit {
+		return AOVSample{}
+	}
+
+	comps := PrepareComputationsWithHit(hit, r, xs)
+	object := *comps.Object
+	material := object.GetNewMaterial()
+
+	return AOVSample{
+		Depth:      comps.Time * r.Direction.Mag(),
+		Normal:     comps.Normalv,
+		Position:   comps.Point,
+		Albedo:     Albedo(material, object, comps.Point),
+		ObjectID:   ids.objects[object],
+		MaterialID: ids.materials[material],
+	}
+}
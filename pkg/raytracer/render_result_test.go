@@ -0,0 +1,128 @@
+package raytracer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderBuffers(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2).SetIntegrator(NewWhitted())
+	c.Samples = 1
+	c.SetTransform(ViewTransform(NewPoint(0, 0, -5), NewPoint(0, 0, 0), NewVec(0, 1, 0)))
+
+	result := c.RenderBuffers(w)
+
+	testCases := []struct {
+		desc   string
+		buffer *Canvas
+		x, y   int
+		want   Color
+	}{
+		{"Beauty", result.Beauty, 5, 5, NewColor(0.380661, 0.475826, 0.285495)},
+		{"Depth", result.Depth, 5, 5, NewColor(4, 4, 4)},
+		{"Normal", result.Normal, 5, 5, NewColor(0, 0, -1)},
+		{"Position", result.Position, 5, 5, NewColor(0, 0, -1)},
+		{"Albedo", result.Albedo, 5, 5, NewColor(0.8, 1.0, 0.6)},
+		{"Object ID", result.ObjectID, 5, 5, NewColor(1, 1, 1)},
+		{"Material ID", result.MaterialID, 5, 5, NewColor(1, 1, 1)},
+		{"Missed depth", result.Depth, 0, 0, NewColor(0, 0, 0)},
+		{"Missed object ID", result.ObjectID, 0, 0, NewColor(0, 0, 0)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := tC.buffer.GetPixel(tC.x, tC.y)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestSceneIDs(t *testing.T) {
+	build := func() (*World, *Sphere, *Sphere, *Cube) {
+		shared := NewDiffuse(NewColor(1, 0, 0))
+
+		s1 := NewSphere()
+		s1.SetNewMaterial(shared)
+		s2 := NewSphere()
+		s2.SetNewMaterial(shared)
+		c := NewCube()
+
+		g := NewGroup()
+		g.AddChild(s2)
+		g.AddChild(NewCSG(Union, c, NewSphere()))
+
+		w := NewWorld()
+		w.AddObject(s1)
+		w.AddObject(g)
+
+		return w, s1, s2, c
+	}
+
+	w, s1, s2, c := build()
+	ids := newSceneIDs(w)
+
+	if ids.objects[s1] != 1 || ids.objects[s2] != 2 || ids.objects[c] != 3 {
+		t.Errorf("Got object ids %v, %v, %v, want 1, 2, 3", ids.objects[s1], ids.objects[s2], ids.objects[c])
+	}
+
+	if ids.materials[s1.GetNewMaterial()] != 1 || ids.materials[c.GetNewMaterial()] != 2 {
+		t.Errorf("Got material ids %v, %v, want 1, 2", ids.materials[s1.GetNewMaterial()], ids.materials[c.GetNewMaterial()])
+	}
+
+	w2, _, s2b, _ := build()
+	ids2 := newSceneIDs(w2)
+
+	if ids2.objects[s2b] != ids.objects[s2] {
+		t.Errorf("IDs not stable between identical worlds, got %v, want %v", ids2.objects[s2b], ids.objects[s2])
+	}
+}
+
+func TestIDColor(t *testing.T) {
+	if !idColor(NewColor(0, 0, 0)).Eq(colorBlack) {
+		t.Error("ID 0 should be black")
+	}
+
+	if idColor(NewColor(1, 1, 1)).Eq(idColor(NewColor(2, 2, 2))) {
+		t.Error("Different IDs should have different colors")
+	}
+}
+
+func TestCanvasNormalized(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.SetPixel(0, 0, NewColor(2, 4, 6))
+	c.SetPixel(1, 0, NewColor(10, math.Inf(1), 4))
+
+	n := c.Normalized()
+
+	if !n.GetPixel(0, 0).Eq(NewColor(0, 0.25, 0.5)) {
+		t.Errorf("Got %v, want %v", n.GetPixel(0, 0), NewColor(0, 0.25, 0.5))
+	}
+
+	if c.GetPixel(0, 0).R != 2 {
+		t.Error("Normalized should not modify the canvas")
+	}
+}
+
+func TestRenderResultSavePNGs(t *testing.T) {
+	rr := NewRenderResult(2, 2)
+	dir := t.TempDir()
+
+	if err := rr.SavePNGs(filepath.Join(dir, "render")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range rr.Buffers() {
+		if _, err := os.Stat(filepath.Join(dir, "render-"+name+".png")); err != nil {
+			t.Errorf("Got %v for the %s buffer, want it saved", err, name)
+		}
+	}
+
+	if err := rr.SavePNGs(filepath.Join(dir, "missing", "render")); err == nil {
+		t.Error("Got no error, want one")
+	}
+}
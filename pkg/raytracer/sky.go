@@ -0,0 +1,246 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// skyLuminanceScale converts the kcd/m² of the Preetham model into the
+// radiance units used by the renderer, putting a clear sky around 0.3-0.5
+const skyLuminanceScale = 1.0 / 20.0
+
+// sunIlluminance is the illuminance from the sun above the atmosphere in
+// klx, before it is attenuated by the atmosphere
+const sunIlluminance = 110.0
+
+// Sky is the analytic daylight model by Preetham, Shirley and Smits, with a
+// sun disc. It is parameterized by the direction towards the sun and by the
+// turbidity of the atmosphere, from 2 (very clear) to 10 (hazy).
+type Sky struct {
+	SunDirection Vec
+	Turbidity    float64
+	Intensity    float64
+	SunSize      float64
+	Ground       Color
+
+	perezY, perezX, perezYc [5]float64
+	zenith                  [3]float64
+	sunTheta                float64
+	sunRadiance             Color
+	distribution            *EnvironmentMap
+}
+
+// NewSky returns a sky with the sun in the given direction. The sun has its
+// real angular radius and the ground below the horizon is black.
+func NewSky(sunDirection Vec, turbidity float64) *Sky {
+	s := &Sky{
+		SunDirection: sunDirection.Norm(),
+		Turbidity:    turbidity,
+		Intensity:    1,
+		SunSize:      0.00465,
+		Ground:       NewColor(0, 0, 0),
+	}
+
+	s.Update()
+
+	return s
+}
+
+// Update recomputes the model after any of the exported fields change
+func (s *Sky) Update() {
+	t := s.Turbidity
+	s.SunDirection = s.SunDirection.Norm()
+	s.sunTheta = math.Acos(math.Max(-1, math.Min(1, s.SunDirection.Y)))
+
+	s.perezY = [5]float64{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703}
+	s.perezX = [5]float64{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452}
+	s.perezYc = [5]float64{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529}
+
+	theta := math.Min(s.sunTheta, math.Pi/2)
+	theta2 := theta * theta
+	theta3 := theta2 * theta
+
+	chi := (4.0/9.0 - t/120.0) * (math.Pi - 2*theta)
+	s.zenith[0] = (4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192
+	s.zenith[1] = t*t*(0.00166*theta3-0.00375*theta2+0.00209*theta) +
+		t*(-0.02903*theta3+0.06377*theta2-0.03202*theta+0.00394) +
+		(0.11693*theta3 - 0.21196*theta2 + 0.06052*theta + 0.25886)
+	s.zenith[2] = t*t*(0.00275*theta3-0.00610*theta2+0.00317*theta) +
+		t*(-0.04214*theta3+0.08970*theta2-0.04153*theta+0.00516) +
+		(0.15346*theta3 - 0.26756*theta2 + 0.06670*theta + 0.26688)
+
+	s.sunRadiance = colorBlack
+	if s.SunDirection.Y > 0 {
+		solidAngle := 2 * math.Pi * (1 - math.Cos(s.SunSize))
+		irradiance := s.sunTransmittance().MulFloat(sunIlluminance * skyLuminanceScale)
+		s.sunRadiance = irradiance.MulFloat(1 / solidAngle)
+	}
+
+	s.distribution = NewEnvironmentMap(s.bake(64, 32))
+}
+
+// ColorAt returns the radiance of the sky, including the sun disc
+func (s *Sky) ColorAt(direction Vec) Color {
+	direction = direction.Norm()
+
+	if direction.Y < 0 {
+		return s.Ground
+	}
+
+	color := s.skyColor(direction)
+
+	if s.inSun(direction) {
+		color = color.Add(s.sunRadiance)
+	}
+
+	return color.MulFloat(s.Intensity)
+}
+
+// Sample chooses the sun disc half of the time when it is above the horizon
+// and otherwise samples the sky by luminance
+func (s *Sky) Sample(source *rand.Rand) (Vec, float64) {
+	var direction Vec
+
+	if s.sunVisible() && source.Float64() < 0.5 {
+		direction = sampleCone(s.SunDirection, math.Cos(s.SunSize), source)
+	} else {
+		direction, _ = s.distribution.Sample(source)
+	}
+
+	return direction, s.Pdf(direction)
+}
+
+func (s *Sky) Pdf(direction Vec) float64 {
+	skyPdf := s.distribution.Pdf(direction)
+
+	if !s.sunVisible() {
+		return skyPdf
+	}
+
+	var sunPdf float64
+	if s.inSun(direction) {
+		sunPdf = 1 / (2 * math.Pi * (1 - math.Cos(s.SunSize)))
+	}
+
+	return 0.5*sunPdf + 0.5*skyPdf
+}
+
+// SunLight returns a distant point light matching the sun, for integrators
+// that shade with lights. Its intensity is the sun irradiance divided by π so
+// Phong diffuse shading matches a Lambertian surface.
+func (s *Sky) SunLight() *PointLight {
+	position := NewPoint(0, 0, 0).AddVec(s.SunDirection.Mul(1e6))
+	solidAngle := 2 * math.Pi * (1 - math.Cos(s.SunSize))
+	intensity := s.sunRadiance.MulFloat(solidAngle * s.Intensity / math.Pi)
+
+	return NewPointLight(position, intensity)
+}
+
+func (s *Sky) sunVisible() bool {
+	return s.SunDirection.Y > 0
+}
+
+func (s *Sky) inSun(direction Vec) bool {
+	return direction.Norm().Dot(s.SunDirection) >= math.Cos(s.SunSize)
+}
+
+// skyColor evaluates the Preetham model without the sun and converts it
+// from xyY to linear sRGB
+func (s *Sky) skyColor(direction Vec) Color {
+	cosTheta := math.Max(direction.Y, 1e-3)
+	theta := math.Acos(cosTheta)
+	gamma := math.Acos(math.Max(-1, math.Min(1, direction.Dot(s.SunDirection))))
+
+	Y := s.zenith[0] * perez(s.perezY, theta, gamma) / perez(s.perezY, 0, s.sunTheta)
+	x := s.zenith[1] * perez(s.perezX, theta, gamma) / perez(s.perezX, 0, s.sunTheta)
+	y := s.zenith[2] * perez(s.perezYc, theta, gamma) / perez(s.perezYc, 0, s.sunTheta)
+
+	return xyYToRGB(x, y, math.Max(Y, 0)*skyLuminanceScale)
+}
+
+// sunTransmittance approximates the color of sunlight after Rayleigh and
+// aerosol scattering for red, green and blue wavelengths in micrometers
+func (s *Sky) sunTransmittance() Color {
+	thetaDegrees := s.sunTheta * 180 / math.Pi
+	mass := 1 / (math.Cos(s.sunTheta) + 0.15*math.Pow(93.885-thetaDegrees, -1.253))
+
+	beta := 0.04608*s.Turbidity - 0.04586
+	alpha := 1.3
+
+	transmittance := func(lambda float64) float64 {
+		rayleigh := math.Exp(-0.008735 * math.Pow(lambda, -4.08) * mass)
+		aerosol := math.Exp(-beta * math.Pow(lambda, -alpha) * mass)
+
+		return rayleigh * aerosol
+	}
+
+	return NewColor(transmittance(0.65), transmittance(0.57), transmittance(0.475))
+}
+
+// bake renders the sky without the sun into an equirectangular canvas
+func (s *Sky) bake(width, height int) *Canvas {
+	canvas := NewCanvas(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			direction := directionFromUV((float64(x)+0.5)/float64(width), (float64(y)+0.5)/float64(height))
+
+			if direction.Y < 0 {
+				canvas.SetPixel(x, y, s.Ground)
+				continue
+			}
+
+			canvas.SetPixel(x, y, s.skyColor(direction))
+		}
+	}
+
+	return canvas
+}
+
+func perez(c [5]float64, theta, gamma float64) float64 {
+	cosGamma := math.Cos(gamma)
+
+	return (1 + c[0]*math.Exp(c[1]/math.Cos(theta))) * (1 + c[2]*math.Exp(c[3]*gamma) + c[4]*cosGamma*cosGamma)
+}
+
+func xyYToRGB(x, y, Y float64) Color {
+	if y <= 0 {
+		return colorBlack
+	}
+
+	X := x / y * Y
+	Z := (1 - x - y) / y * Y
+
+	return NewColor(
+		math.Max(3.2406*X-1.5372*Y-0.4986*Z, 0),
+		math.Max(-0.9689*X+1.8758*Y+0.0415*Z, 0),
+		math.Max(0.0557*X-0.2040*Y+1.0570*Z, 0),
+	)
+}
+
+// sampleCone picks a uniformly distributed direction within the cone around
+// axis whose half angle has the given cosine
+func sampleCone(axis Vec, cosThetaMax float64, source *rand.Rand) Vec {
+	cosTheta := 1 - source.Float64()*(1-cosThetaMax)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * source.Float64()
+
+	u, v := orthonormalBasis(axis)
+
+	return u.Mul(math.Cos(phi) * sinTheta).Add(v.Mul(math.Sin(phi) * sinTheta)).Add(axis.Mul(cosTheta)).Norm()
+}
+
+// orthonormalBasis returns two unit vectors perpendicular to n and each other
+func orthonormalBasis(n Vec) (Vec, Vec) {
+	var a Vec
+	if math.Abs(n.X) > 0.9 {
+		a = NewVec(0, 1, 0)
+	} else {
+		a = NewVec(1, 0, 0)
+	}
+
+	u := n.Cross(a).Norm()
+	v := n.Cross(u)
+
+	return u, v
+}
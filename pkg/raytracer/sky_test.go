@@ -0,0 +1,118 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSkyIsBlueAndBrightestNearSun(t *testing.T) {
+	sky := NewSky(NewVec(0, 1, -1), 3)
+
+	zenith := sky.ColorAt(NewVec(0, 1, 0))
+
+	if zenith.B <= zenith.R {
+		t.Errorf("Got %v, want a blue sky", zenith)
+	}
+
+	nearSun := sky.ColorAt(NewVec(0, 1, -1.2))
+	awayFromSun := sky.ColorAt(NewVec(0, 1, 1.2))
+
+	if Luminance(nearSun) <= Luminance(awayFromSun) {
+		t.Errorf("Got %v near the sun, want brighter than %v", nearSun, awayFromSun)
+	}
+}
+
+func TestSkyGroundAndSunDisc(t *testing.T) {
+	sky := NewSky(NewVec(0, 1, -1), 3)
+	sky.Ground = NewColor(0.2, 0.2, 0.2)
+
+	ground := sky.ColorAt(NewVec(0, -1, 0))
+	if !ground.Eq(NewColor(0.2, 0.2, 0.2)) {
+		t.Errorf("Got %v, want %v", ground, NewColor(0.2, 0.2, 0.2))
+	}
+
+	sun := sky.ColorAt(sky.SunDirection)
+	if Luminance(sun) < 1000 {
+		t.Errorf("Got %v, want a very bright sun", sun)
+	}
+}
+
+func TestSkySunsetIsRedder(t *testing.T) {
+	noon := NewSky(NewVec(0, 1, 0), 3).SunLight().Intensity
+	sunset := NewSky(NewVec(0, 0.05, -1), 3).SunLight().Intensity
+
+	if sunset.B/sunset.R >= noon.B/noon.R {
+		t.Errorf("Got %v at sunset, want redder than %v", sunset, noon)
+	}
+
+	if Luminance(sunset) >= Luminance(noon) {
+		t.Errorf("Got %v at sunset, want darker than %v", sunset, noon)
+	}
+}
+
+func TestSkySunBelowHorizonHasNoLight(t *testing.T) {
+	sky := NewSky(NewVec(0, -1, -1), 3)
+
+	got := sky.SunLight().Intensity
+
+	if !got.Eq(NewColor(0, 0, 0)) {
+		t.Errorf("Got %v, want %v", got, NewColor(0, 0, 0))
+	}
+}
+
+func TestSkySamplesSunAndMatchesPdf(t *testing.T) {
+	sky := NewSky(NewVec(1, 1, 0), 3)
+	source := rand.New(rand.NewSource(1))
+
+	inSun := 0
+	n := 2000
+
+	for i := 0; i < n; i++ {
+		direction, pdf := sky.Sample(source)
+
+		if math.Abs(pdf-sky.Pdf(direction)) > 1e-6*pdf {
+			t.Fatalf("Got pdf %v, want %v", pdf, sky.Pdf(direction))
+		}
+
+		if sky.inSun(direction) {
+			inSun++
+		}
+	}
+
+	if inSun < n/3 {
+		t.Errorf("Got %v samples in the sun, want about %v", inSun, n/2)
+	}
+}
+
+func TestWorldWithSkyLightsPathTracedFloor(t *testing.T) {
+	w := NewWorld()
+	w.Source = rand.New(rand.NewSource(1))
+	w.SetSky(NewSky(NewVec(0, 1, 0), 3))
+
+	floor := NewPlane()
+	floor.SetNewMaterial(NewDiffuse(NewColor(0.5, 0.5, 0.5)))
+	w.AddObject(floor)
+
+	if len(w.Lights) != 1 {
+		t.Fatalf("Got %v lights, want %v", len(w.Lights), 1)
+	}
+
+	r := NewRay(NewPoint(0, 1, 0), NewVec(0, -1, 0))
+
+	var sum Color
+	for i := 0; i < 64; i++ {
+		sum = sum.Add(NewPathTracer().ColorAt(w, &r, 4))
+	}
+	got := sum.MulFloat(1.0 / 64)
+
+	phong := NewWhitted().ColorAt(w, &r, 4)
+
+	if Luminance(got) < 0.5 {
+		t.Errorf("Got %v, want a sunlit floor", got)
+	}
+
+	if Luminance(phong) < 0.5 {
+		t.Errorf("Got %v with Whitted, want a sunlit floor", phong)
+	}
+}
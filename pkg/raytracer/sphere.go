@@ -63,6 +63,10 @@ func (s *Sphere) LocalNormalAt(objectPoint Point, i Intersection) Vec {
 	return objectPoint.Sub(NewPoint(0, 0, 0))
 }
 
+func (s *Sphere) LocalUVAt(objectPoint Point) (u, v float64) {
+	return SphericalMap(objectPoint)
+}
+
 func (s *Sphere) Bounds() *BoundingBox {
 	return NewBoundingBoxWithValues(NewPoint(-1, -1, -1), NewPoint(1, 1, 1)).Transform(s.GetTransform())
 }
@@ -84,8 +84,8 @@ func TestSphereIntersectBehind(t *testing.T) {
 func TestSphereDefaultTransform(t *testing.T) {
 	s := NewSphere()
 
-	if !s.Transform.Eq(NewIdentityMatrix()) {
-		t.Errorf("Sphere default transform is wrong, got %v", s.Transform)
+	if !s.GetTransform().Eq(NewIdentityMatrix()) {
+		t.Errorf("Sphere default transform is wrong, got %v", s.GetTransform())
 	}
 }
 
@@ -94,8 +94,8 @@ func TestSetTransform(t *testing.T) {
 	tf := NewTranslation(2, 3, 4)
 	s.SetTransform(tf)
 
-	if !s.Transform.Eq(tf) {
-		t.Errorf("Sphere set transform got wrong, got %v", s.Transform)
+	if !s.GetTransform().Eq(tf) {
+		t.Errorf("Sphere set transform got wrong, got %v", s.GetTransform())
 	}
 }
 
@@ -209,7 +209,7 @@ func TestSphereNormalAtTransformed(t *testing.T) {
 func TestSphereDefaultMaterial(t *testing.T) {
 	s := NewSphere()
 
-	if *s.Material != *NewMaterial() {
+	if *s.GetNewMaterial().(*Material) != *NewMaterial() {
 		t.Error("Invalid sphere default material")
 	}
 }
@@ -217,12 +217,11 @@ func TestSphereDefaultMaterial(t *testing.T) {
 func TestSphereSetMaterial(t *testing.T) {
 	s := NewSphere()
 
-	mat := NewMaterial()
-	mat.Ambient = 1
+	mat := NewMetal(NewColor(1, 1, 1), 0.5)
 
-	s.Material = mat
+	s.SetNewMaterial(mat)
 
-	if s.Material != mat {
+	if s.GetNewMaterial() != mat {
 		t.Error("Invalid sphere material")
 	}
 }
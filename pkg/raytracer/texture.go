@@ -0,0 +1,53 @@
+package raytracer
+
+// Texture gives a color at a point on an object, which materials use in
+// place of a fixed color
+type Texture interface {
+	ColorAt(object Intersectable, worldPoint Point) Color
+}
+
+// ConstantTexture is the same color everywhere
+type ConstantTexture struct {
+	Color Color
+}
+
+func NewConstantTexture(c Color) *ConstantTexture {
+	return &ConstantTexture{c}
+}
+
+func (ct *ConstantTexture) ColorAt(object Intersectable, worldPoint Point) Color {
+	return ct.Color
+}
+
+// PatternTexture evaluates a pattern in the space of the object it is on,
+// so the pattern moves with the object and every group it is in
+type PatternTexture struct {
+	Pattern Pattern
+}
+
+func NewPatternTexture(p Pattern) *PatternTexture {
+	return &PatternTexture{p}
+}
+
+func (pt *PatternTexture) ColorAt(object Intersectable, worldPoint Point) Color {
+	return pt.Pattern.ColorAtObject(object, worldPoint)
+}
+
+// textureColor returns the color of a texture, or c when there is none
+func textureColor(t Texture, c Color, object Intersectable, worldPoint Point) Color {
+	if t == nil {
+		return c
+	}
+
+	return t.ColorAt(object, worldPoint)
+}
+
+// textureScale scales v by the luminance of a texture, or returns v when
+// there is none. Gray maps for scalar parameters read as their gray level.
+func textureScale(t Texture, v float64, object Intersectable, worldPoint Point) float64 {
+	if t == nil {
+		return v
+	}
+
+	return v * Luminance(t.ColorAt(object, worldPoint))
+}
@@ -0,0 +1,89 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestConstantTexture(t *testing.T) {
+	tex := NewConstantTexture(NewColor(0.1, 0.2, 0.3))
+
+	if got, want := tex.ColorAt(NewSphere(), NewPoint(1, 2, 3)), NewColor(0.1, 0.2, 0.3); !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPatternTextureUsesParentTransforms(t *testing.T) {
+	s := NewSphere()
+	s.SetTransform(NewTranslation(5, 0, 0))
+
+	g := NewGroup()
+	g.SetTransform(NewScaling(2, 2, 2))
+	g.AddChild(s)
+
+	tex := NewPatternTexture(NewStripePattern(white, black))
+
+	testCases := []struct {
+		desc  string
+		point Point
+		want  Color
+	}{
+		// (13, 0, 0) is (6.5, 0, 0) in the group and (1.5, 0, 0) on the sphere
+		{"Through the group", NewPoint(13, 0, 0), black},
+		{"Inside the first stripe", NewPoint(11, 0, 0), white},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tex.ColorAt(s, tC.point); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestMaterialsUseTextures(t *testing.T) {
+	tex := NewPatternTexture(NewStripePattern(NewColor(1, 0, 0), NewColor(0, 0, 1)))
+	s := NewSphere()
+
+	testCases := []struct {
+		desc     string
+		material Scatters
+	}{
+		{"Diffuse", NewDiffuse(white).SetTexture(tex)},
+		{"Metal", NewMetal(white, 0).SetTexture(tex)},
+		{"Material", NewMaterial().SetPattern(NewStripePattern(white, white)).SetTexture(tex)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			for _, p := range []Point{NewPoint(0.5, 0, 0), NewPoint(1.5, 0, 0)} {
+				want := tex.ColorAt(s, p)
+
+				if got := Albedo(tC.material, s, p); !got.Eq(want) {
+					t.Errorf("Got albedo %v at %v, want %v", got, p, want)
+				}
+
+				if got := phongMaterial(tC.material).ColorAt(s, p); !got.Eq(want) {
+					t.Errorf("Got Phong color %v at %v, want %v", got, p, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffuseScatterUsesTexture(t *testing.T) {
+	s := NewSphere()
+	s.SetNewMaterial(NewDiffuse(white).SetTexture(NewPatternTexture(NewTestPattern())))
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+	i := NewIntersection(4, s)
+	comps := PrepareComputations(i, r)
+
+	var attenuation Color
+	var scattered Ray
+
+	s.GetNewMaterial().Scatter(&r, &comps, &attenuation, &scattered, rand.New(rand.NewSource(1)))
+
+	if want := NewColor(0, 0, -1); !attenuation.Eq(want) {
+		t.Errorf("Got %v, want %v", attenuation, want)
+	}
+}
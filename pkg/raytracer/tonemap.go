@@ -0,0 +1,169 @@
+package raytracer
+
+import (
+	"image/color"
+	"math"
+)
+
+// ToneMap compresses linear radiance into the displayable range [0, 1]
+type ToneMap func(Color) Color
+
+// ToneMapClamp leaves colors as they are, so everything above 1 clips
+func ToneMapClamp(c Color) Color {
+	return c
+}
+
+// ToneMapReinhard maps luminance L to L/(1+L), which never quite reaches
+// white but keeps the hue of bright colors
+func ToneMapReinhard(c Color) Color {
+	return scaleLuminance(c, func(l float64) float64 {
+		return l / (1 + l)
+	})
+}
+
+// ToneMapExtendedReinhard is Reinhard where luminance white maps to 1 and
+// anything brighter clips
+func ToneMapExtendedReinhard(white float64) ToneMap {
+	return func(c Color) Color {
+		return scaleLuminance(c, func(l float64) float64 {
+			return l * (1 + l/(white*white)) / (1 + l)
+		})
+	}
+}
+
+// ToneMapACES is Krzysztof Narkowicz's fit of the ACES filmic curve, which
+// adds contrast and desaturates highlights towards white
+func ToneMapACES(c Color) Color {
+	aces := func(x float64) float64 {
+		x = math.Max(x, 0)
+
+		return math.Min((x*(2.51*x+0.03))/(x*(2.43*x+0.59)+0.14), 1)
+	}
+
+	return NewColor(aces(c.R), aces(c.G), aces(c.B))
+}
+
+func scaleLuminance(c Color, f func(float64) float64) Color {
+	l := Luminance(c)
+
+	if l <= 0 {
+		return c
+	}
+
+	return c.MulFloat(f(l) / l)
+}
+
+// Output describes how a linear canvas is turned into an 8 or 16-bit image.
+// Exposure is in stops, each one doubling the brightness before tone mapping,
+// and Transfer encodes the tone mapped values for the file.
+type Output struct {
+	Exposure float64
+	ToneMap  ToneMap
+	Transfer TransferFunction
+	BitDepth int
+}
+
+// NewOutput returns the output used by SavePNG, with no exposure change,
+// colors clamped, sRGB encoding and 8 bits per channel
+func NewOutput() *Output {
+	return &Output{
+		Exposure: 0,
+		ToneMap:  ToneMapClamp,
+		Transfer: SRGB,
+		BitDepth: 8,
+	}
+}
+
+func (o *Output) SetExposure(stops float64) *Output {
+	o.Exposure = stops
+
+	return o
+}
+
+func (o *Output) SetToneMap(tm ToneMap) *Output {
+	o.ToneMap = tm
+
+	return o
+}
+
+func (o *Output) SetTransfer(t TransferFunction) *Output {
+	o.Transfer = t
+
+	return o
+}
+
+// SetBitDepth sets the bits per channel of PNG files, 8 or 16
+func (o *Output) SetBitDepth(bits int) *Output {
+	o.BitDepth = bits
+
+	return o
+}
+
+// Apply exposes and tone maps a linear color
+func (o *Output) Apply(c Color) Color {
+	c = c.MulFloat(math.Pow(2, o.Exposure))
+
+	if o.ToneMap != nil {
+		c = o.ToneMap(c)
+	}
+
+	return c
+}
+
+// Encode exposes, tone maps and encodes a linear color into [0, 1]
+func (o *Output) Encode(c Color) Color {
+	c = o.Apply(c)
+
+	if o.Transfer != nil {
+		clamp := func(v float64) float64 {
+			return o.Transfer.Encode(math.Min(math.Max(v, 0), 1))
+		}
+
+		c = NewColor(clamp(c.R), clamp(c.G), clamp(c.B))
+	}
+
+	return c
+}
+
+// RGBA converts a linear color to 8-bit
+func (o *Output) RGBA(c Color) color.RGBA {
+	c = o.Encode(c)
+
+	return color.RGBA{
+		uint8(getColorValue(c.R)),
+		uint8(getColorValue(c.G)),
+		uint8(getColorValue(c.B)),
+		0xFF,
+	}
+}
+
+// Pixel converts a linear color premultiplied by alpha to a color with
+// straight alpha at the bit depth of the output
+func (o *Output) Pixel(c Color, alpha float64) color.Color {
+	alpha = math.Min(math.Max(alpha, 0), 1)
+
+	if alpha == 0 {
+		if o.BitDepth == 16 {
+			return color.NRGBA64{}
+		}
+
+		return color.NRGBA{}
+	}
+
+	c = o.Encode(c.MulFloat(1 / alpha))
+
+	if o.BitDepth == 16 {
+		channel := func(v float64) uint16 {
+			return uint16(math.Round(math.Min(math.Max(v, 0), 1) * 0xFFFF))
+		}
+
+		return color.NRGBA64{channel(c.R), channel(c.G), channel(c.B), channel(alpha)}
+	}
+
+	return color.NRGBA{
+		uint8(getColorValue(c.R)),
+		uint8(getColorValue(c.G)),
+		uint8(getColorValue(c.B)),
+		uint8(getColorValue(alpha)),
+	}
+}
@@ -0,0 +1,85 @@
+package raytracer
+
+import (
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToneMaps(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		toneMap ToneMap
+		in      Color
+		want    Color
+	}{
+		{desc: "Clamp leaves colors", toneMap: ToneMapClamp, in: NewColor(2, 0.5, 0), want: NewColor(2, 0.5, 0)},
+		{desc: "Reinhard halves luminance 1", toneMap: ToneMapReinhard, in: NewColor(1, 1, 1), want: NewColor(0.5, 0.5, 0.5)},
+		{desc: "Reinhard keeps black", toneMap: ToneMapReinhard, in: NewColor(0, 0, 0), want: NewColor(0, 0, 0)},
+		{desc: "Extended Reinhard white point", toneMap: ToneMapExtendedReinhard(4), in: NewColor(4, 4, 4), want: NewColor(1, 1, 1)},
+		{desc: "ACES black", toneMap: ToneMapACES, in: NewColor(0, 0, 0), want: NewColor(0, 0, 0)},
+		{desc: "ACES mid gray", toneMap: ToneMapACES, in: NewColor(0.18, 0.18, 0.18), want: NewColor(0.266899, 0.266899, 0.266899)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := tC.toneMap(tC.in)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestToneMapsStayInRange(t *testing.T) {
+	toneMaps := []ToneMap{ToneMapReinhard, ToneMapExtendedReinhard(100), ToneMapACES}
+
+	for _, tm := range toneMaps {
+		previous := 0.0
+
+		for _, v := range []float64{0.01, 0.1, 1, 5, 10, 100} {
+			got := Luminance(tm(NewColor(v, v, v)))
+
+			if got < previous || got > 1+1e-9 {
+				t.Errorf("Got %v for %v after %v, want non-decreasing values in [0, 1]", got, v, previous)
+			}
+
+			previous = got
+		}
+	}
+}
+
+func TestOutputExposure(t *testing.T) {
+	output := NewOutput().SetExposure(1).SetTransfer(Linear)
+
+	got := output.RGBA(NewColor(0.25, 0.5, 1))
+	want := color.RGBA{128, 255, 255, 255}
+
+	if got != want {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	canvas := uniformCanvas(2, 2, NewColor(1, 1, 1)).ToneMapped(NewOutput().SetExposure(-1).SetToneMap(ToneMapReinhard))
+	if p := canvas.GetPixel(1, 1); math.Abs(p.R-1.0/3) > 1e-9 {
+		t.Errorf("Got %v, want %v", p.R, 1.0/3)
+	}
+}
+
+func TestSavePNGWithOutput(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "out.png")
+
+	uniformCanvas(2, 2, NewColor(3, 3, 3)).SavePNGWithOutput(filename, NewOutput().SetToneMap(ToneMapReinhard))
+
+	loaded, err := LoadCanvas(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.GetPixel(0, 0).R; math.Abs(got-0.75) > 1.0/255 {
+		t.Errorf("Got %v, want %v", got, 0.75)
+	}
+
+	os.Remove(filename)
+}
@@ -0,0 +1,56 @@
+package raytracer
+
+import (
+	"math"
+)
+
+// TransferFunction converts between linear values and the values stored in
+// an image file
+type TransferFunction interface {
+	Encode(linear float64) float64
+	Decode(encoded float64) float64
+}
+
+// SRGB is the exact sRGB transfer curve, a linear segment near black and a
+// 2.4 power curve above it
+var SRGB TransferFunction = srgbTransfer{}
+
+// Linear stores values as they are
+var Linear TransferFunction = GammaTransfer(1)
+
+type srgbTransfer struct{}
+
+func (srgbTransfer) Encode(linear float64) float64 {
+	if linear <= 0.0031308 {
+		return 12.92 * linear
+	}
+
+	return 1.055*math.Pow(linear, 1/2.4) - 0.055
+}
+
+func (srgbTransfer) Decode(encoded float64) float64 {
+	if encoded <= 0.04045 {
+		return encoded / 12.92
+	}
+
+	return math.Pow((encoded+0.055)/1.055, 2.4)
+}
+
+// GammaTransfer is a plain power curve, values are stored as linear^(1/gamma)
+type GammaTransfer float64
+
+func (g GammaTransfer) Encode(linear float64) float64 {
+	if linear <= 0 {
+		return 0
+	}
+
+	return math.Pow(linear, 1/float64(g))
+}
+
+func (g GammaTransfer) Decode(encoded float64) float64 {
+	if encoded <= 0 {
+		return 0
+	}
+
+	return math.Pow(encoded, float64(g))
+}
@@ -0,0 +1,70 @@
+package raytracer
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTransferFunctions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		transfer TransferFunction
+		linear   float64
+		encoded  float64
+	}{
+		{desc: "sRGB black", transfer: SRGB, linear: 0, encoded: 0},
+		{desc: "sRGB linear segment", transfer: SRGB, linear: 0.002, encoded: 0.02584},
+		{desc: "sRGB mid gray", transfer: SRGB, linear: 0.214041, encoded: 0.5},
+		{desc: "sRGB white", transfer: SRGB, linear: 1, encoded: 1},
+		{desc: "Gamma 2.2", transfer: GammaTransfer(2.2), linear: 0.217638, encoded: 0.5},
+		{desc: "Linear", transfer: Linear, linear: 0.5, encoded: 0.5},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tC.transfer.Encode(tC.linear); math.Abs(got-tC.encoded) > 1e-5 {
+				t.Errorf("Encode got %v, want %v", got, tC.encoded)
+			}
+
+			if got := tC.transfer.Decode(tC.encoded); math.Abs(got-tC.linear) > 1e-5 {
+				t.Errorf("Decode got %v, want %v", got, tC.linear)
+			}
+		})
+	}
+}
+
+func TestPPMIsSRGBEncoded(t *testing.T) {
+	canvas := NewCanvas(1, 1)
+	canvas.SetPixel(0, 0, NewColor(0.5, 0.215861, 1))
+
+	got := strings.Split(canvas.GetPPMString(), "\n")[3]
+
+	if got != "188 128 255" {
+		t.Errorf("Got %v, want %v", got, "188 128 255")
+	}
+}
+
+func TestCanvasFromImageDecodesSRGB(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{128, 255, 0, 255})
+
+	testCases := []struct {
+		desc     string
+		transfer TransferFunction
+		want     Color
+	}{
+		{desc: "sRGB", transfer: SRGB, want: NewColor(0.215861, 1, 0)},
+		{desc: "Linear", transfer: Linear, want: NewColor(0.501961, 1, 0)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := NewCanvasFromImageWithTransfer(img, tC.transfer).GetPixel(0, 0)
+
+			if !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
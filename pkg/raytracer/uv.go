@@ -0,0 +1,190 @@
+package raytracer
+
+import "math"
+
+// UVMapping turns a point in object space into texture coordinates, with u
+// running left to right and v bottom to top, both in [0, 1)
+type UVMapping func(p Point) (u, v float64)
+
+// UVAt returns the texture coordinates of a world point on an object. An
+// object uses the mapping set with SetUVMapping, then the natural mapping of
+// its shape, and a planar mapping when it has neither.
+func UVAt(object Intersectable, worldPoint Point) (u, v float64) {
+	objectPoint := object.WorldToObject(worldPoint)
+
+	if m, ok := object.(uvMapped); ok && m.GetUVMapping() != nil {
+		return m.GetUVMapping()(objectPoint)
+	}
+
+	if n, ok := object.(naturalUV); ok {
+		return n.LocalUVAt(objectPoint)
+	}
+
+	return PlanarMap(objectPoint)
+}
+
+type uvMapped interface {
+	GetUVMapping() UVMapping
+}
+
+// naturalUV is implemented by shapes that have a mapping of their own
+type naturalUV interface {
+	LocalUVAt(objectPoint Point) (u, v float64)
+}
+
+// SphericalMap wraps u around the y axis, starting and ending at -z, and v
+// from the bottom pole to the top one
+func SphericalMap(p Point) (u, v float64) {
+	radius := NewVec(p.X, p.Y, p.Z).Mag()
+	if radius == 0 {
+		return 0, 0
+	}
+
+	phi := math.Acos(math.Max(-1, math.Min(1, p.Y/radius)))
+
+	return azimuthU(p), 1 - phi/math.Pi
+}
+
+// PlanarMap repeats the unit square across the xz plane
+func PlanarMap(p Point) (u, v float64) {
+	return fract(p.X), fract(p.Z)
+}
+
+// CylindricalMap wraps u around the y axis like SphericalMap and repeats v
+// every unit of y
+func CylindricalMap(p Point) (u, v float64) {
+	return azimuthU(p), fract(p.Y)
+}
+
+// CubeFace is a face of the cube from -1 to 1 on every axis
+type CubeFace int
+
+const (
+	CubeLeft CubeFace = iota
+	CubeFront
+	CubeRight
+	CubeBack
+	CubeUp
+	CubeDown
+)
+
+// CubeFaceAt returns the face a point is closest to
+func CubeFaceAt(p Point) CubeFace {
+	x, y, z := math.Abs(p.X), math.Abs(p.Y), math.Abs(p.Z)
+
+	switch {
+	case x >= y && x >= z:
+		if p.X > 0 {
+			return CubeRight
+		}
+		return CubeLeft
+	case y >= z:
+		if p.Y > 0 {
+			return CubeUp
+		}
+		return CubeDown
+	default:
+		if p.Z > 0 {
+			return CubeFront
+		}
+		return CubeBack
+	}
+}
+
+// CubeFaceUV maps a point to the face it is on and to coordinates within
+// that face, as seen from outside the cube with up pointing to +y, or to -z
+// on the top face and +z on the bottom one
+func CubeFaceUV(p Point) (face CubeFace, u, v float64) {
+	face = CubeFaceAt(p)
+
+	switch face {
+	case CubeLeft:
+		u, v = p.Z+1, p.Y+1
+	case CubeFront:
+		u, v = p.X+1, p.Y+1
+	case CubeRight:
+		u, v = 1-p.Z, p.Y+1
+	case CubeBack:
+		u, v = 1-p.X, p.Y+1
+	case CubeUp:
+		u, v = p.X+1, 1-p.Z
+	case CubeDown:
+		u, v = p.X+1, p.Z+1
+	}
+
+	return face, math.Mod(u, 2) / 2, math.Mod(v, 2) / 2
+}
+
+// cubeCross places each face in a 4 by 3 cross, counted in faces from the
+// bottom left corner. The middle row goes left, front, right and back, with
+// up above front and down below it.
+var cubeCross = map[CubeFace][2]float64{
+	CubeLeft:  {0, 1},
+	CubeFront: {1, 1},
+	CubeRight: {2, 1},
+	CubeBack:  {3, 1},
+	CubeUp:    {1, 2},
+	CubeDown:  {1, 0},
+}
+
+// CubeMap puts all six faces of a cube in one texture laid out as a cross,
+// the usual layout for skyboxes and box textures
+func CubeMap(p Point) (u, v float64) {
+	face, fu, fv := CubeFaceUV(p)
+	cell := cubeCross[face]
+
+	return (cell[0] + fu) / 4, (cell[1] + fv) / 3
+}
+
+// azimuthU is the angle around the y axis as a fraction of a turn, growing
+// counterclockwise when seen from above
+func azimuthU(p Point) float64 {
+	theta := math.Atan2(p.X, p.Z)
+
+	return fract(1 - (theta/(2*math.Pi) + 0.5))
+}
+
+// discUV maps a point on a cap of the given radius onto the unit square
+func discUV(p Point, radius float64) (u, v float64) {
+	if radius == 0 {
+		return 0.5, 0.5
+	}
+
+	return (p.X/radius + 1) / 2, (1 - p.Z/radius) / 2
+}
+
+// sideV spreads v over the height of a truncated shape, or repeats it every
+// unit when the shape is infinite
+func sideV(y, minimum, maximum float64) float64 {
+	if math.IsInf(minimum, 0) || math.IsInf(maximum, 0) {
+		return fract(y)
+	}
+
+	return (y - minimum) / (maximum - minimum)
+}
+
+// fract is the positive fractional part of f
+func fract(f float64) float64 {
+	return f - math.Floor(f)
+}
+
+// UVCheckerTexture is a checkerboard in texture space with Width by Height
+// squares, handy to see how a mapping lays out on a shape
+type UVCheckerTexture struct {
+	Width, Height int
+	A, B          Color
+}
+
+func NewUVCheckerTexture(width, height int, a, b Color) *UVCheckerTexture {
+	return &UVCheckerTexture{width, height, a, b}
+}
+
+func (uc *UVCheckerTexture) ColorAt(object Intersectable, worldPoint Point) Color {
+	u, v := UVAt(object, worldPoint)
+
+	if (int(math.Floor(u*float64(uc.Width)))+int(math.Floor(v*float64(uc.Height))))%2 == 0 {
+		return uc.A
+	}
+
+	return uc.B
+}
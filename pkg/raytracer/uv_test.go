@@ -0,0 +1,210 @@
+package raytracer
+
+import (
+	"math"
+	"testing"
+)
+
+type uvCase struct {
+	desc  string
+	point Point
+	u, v  float64
+}
+
+func checkUV(t *testing.T, mapping UVMapping, testCases []uvCase) {
+	t.Helper()
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			u, v := mapping(tC.point)
+
+			if !WithinTolerance(u, tC.u, 1e-4) || !WithinTolerance(v, tC.v, 1e-4) {
+				t.Errorf("Got %v, %v, want %v, %v", u, v, tC.u, tC.v)
+			}
+		})
+	}
+}
+
+func TestSphericalMap(t *testing.T) {
+	checkUV(t, SphericalMap, []uvCase{
+		{"Back", NewPoint(0, 0, -1), 0, 0.5},
+		{"Right", NewPoint(1, 0, 0), 0.25, 0.5},
+		{"Front", NewPoint(0, 0, 1), 0.5, 0.5},
+		{"Left", NewPoint(-1, 0, 0), 0.75, 0.5},
+		{"Top", NewPoint(0, 1, 0), 0.5, 1},
+		{"Bottom", NewPoint(0, -1, 0), 0.5, 0},
+		{"Between", NewPoint(math.Sqrt2/2, math.Sqrt2/2, 0), 0.25, 0.75},
+	})
+}
+
+func TestPlanarMap(t *testing.T) {
+	checkUV(t, PlanarMap, []uvCase{
+		{"Inside the unit square", NewPoint(0.25, 0, 0.5), 0.25, 0.5},
+		{"Negative z", NewPoint(0.25, 0, -0.25), 0.25, 0.75},
+		{"Ignores y", NewPoint(0.25, 0.5, -0.25), 0.25, 0.75},
+		{"Repeats in x", NewPoint(1.25, 0, 0.5), 0.25, 0.5},
+		{"Repeats in z", NewPoint(0.25, 0, -1.75), 0.25, 0.25},
+		{"Corner", NewPoint(1, 0, -1), 0, 0},
+	})
+}
+
+func TestCylindricalMap(t *testing.T) {
+	checkUV(t, CylindricalMap, []uvCase{
+		{"Back", NewPoint(0, 0, -1), 0, 0},
+		{"Back halfway up", NewPoint(0, 0.5, -1), 0, 0.5},
+		{"Repeats in y", NewPoint(0, 1, -1), 0, 0},
+		{"Back right", NewPoint(0.70711, 0.5, -0.70711), 0.125, 0.5},
+		{"Right", NewPoint(1, 0.5, 0), 0.25, 0.5},
+		{"Front right", NewPoint(0.70711, 0.5, 0.70711), 0.375, 0.5},
+		{"Front below", NewPoint(0, -0.25, 1), 0.5, 0.75},
+		{"Front left", NewPoint(-0.70711, 0.5, 0.70711), 0.625, 0.5},
+		{"Left", NewPoint(-1, 1.25, 0), 0.75, 0.25},
+		{"Back left", NewPoint(-0.70711, 0.5, -0.70711), 0.875, 0.5},
+	})
+}
+
+func TestCubeFaceAt(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		point Point
+		want  CubeFace
+	}{
+		{"Left", NewPoint(-1, 0.5, -0.25), CubeLeft},
+		{"Right", NewPoint(1.1, -0.75, 0.8), CubeRight},
+		{"Front", NewPoint(0.1, 0.6, 0.9), CubeFront},
+		{"Back", NewPoint(-0.7, 0, -2), CubeBack},
+		{"Up", NewPoint(0.5, 1, 0.9), CubeUp},
+		{"Down", NewPoint(-0.2, -1.3, 1.1), CubeDown},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := CubeFaceAt(tC.point); got != tC.want {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestCubeFaceUV(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		point Point
+		face  CubeFace
+		u, v  float64
+	}{
+		{"Front upper left", NewPoint(-0.5, 0.5, 1), CubeFront, 0.25, 0.75},
+		{"Front lower right", NewPoint(0.5, -0.5, 1), CubeFront, 0.75, 0.25},
+		{"Back upper left", NewPoint(0.5, 0.5, -1), CubeBack, 0.25, 0.75},
+		{"Back lower right", NewPoint(-0.5, -0.5, -1), CubeBack, 0.75, 0.25},
+		{"Left upper left", NewPoint(-1, 0.5, -0.5), CubeLeft, 0.25, 0.75},
+		{"Left lower right", NewPoint(-1, -0.5, 0.5), CubeLeft, 0.75, 0.25},
+		{"Right upper left", NewPoint(1, 0.5, 0.5), CubeRight, 0.25, 0.75},
+		{"Right lower right", NewPoint(1, -0.5, -0.5), CubeRight, 0.75, 0.25},
+		{"Up upper left", NewPoint(-0.5, 1, -0.5), CubeUp, 0.25, 0.75},
+		{"Up lower right", NewPoint(0.5, 1, 0.5), CubeUp, 0.75, 0.25},
+		{"Down upper left", NewPoint(-0.5, -1, 0.5), CubeDown, 0.25, 0.75},
+		{"Down lower right", NewPoint(0.5, -1, -0.5), CubeDown, 0.75, 0.25},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			face, u, v := CubeFaceUV(tC.point)
+
+			if face != tC.face || !WithinTolerance(u, tC.u, 1e-9) || !WithinTolerance(v, tC.v, 1e-9) {
+				t.Errorf("Got %v %v, %v, want %v %v, %v", face, u, v, tC.face, tC.u, tC.v)
+			}
+		})
+	}
+}
+
+func TestCubeMap(t *testing.T) {
+	checkUV(t, CubeMap, []uvCase{
+		{"Left", NewPoint(-1, 0.5, -0.5), 0.25 / 4, 1.75 / 3},
+		{"Front", NewPoint(-0.5, 0.5, 1), 1.25 / 4, 1.75 / 3},
+		{"Right", NewPoint(1, 0.5, 0.5), 2.25 / 4, 1.75 / 3},
+		{"Back", NewPoint(0.5, 0.5, -1), 3.25 / 4, 1.75 / 3},
+		{"Up", NewPoint(-0.5, 1, -0.5), 1.25 / 4, 2.75 / 3},
+		{"Down", NewPoint(-0.5, -1, 0.5), 1.25 / 4, 0.75 / 3},
+	})
+}
+
+func TestNaturalUVs(t *testing.T) {
+	cylinder := NewCylinder()
+	cylinder.Minimum = 1
+	cylinder.Maximum = 3
+	cylinder.Closed = true
+
+	cone := NewCone()
+	cone.Minimum = -2
+	cone.Maximum = 0
+	cone.Closed = true
+
+	infinite := NewCylinder()
+
+	testCases := []struct {
+		desc   string
+		object Intersectable
+		point  Point
+		u, v   float64
+	}{
+		{"Sphere", NewSphere(), NewPoint(1, 0, 0), 0.25, 0.5},
+		{"Plane", NewPlane(), NewPoint(1.25, 0, -0.25), 0.25, 0.75},
+		{"Cube", NewCube(), NewPoint(-0.5, 0.5, 1), 1.25 / 4, 1.75 / 3},
+		{"Cylinder side spans its height", cylinder, NewPoint(1, 1.5, 0), 0.25, 0.25},
+		{"Cylinder top cap", cylinder, NewPoint(0.5, 3, 0.5), 0.75, 0.25},
+		{"Cylinder bottom cap", cylinder, NewPoint(-0.5, 1, -0.5), 0.25, 0.75},
+		{"Infinite cylinder repeats", infinite, NewPoint(0, 5.25, 1), 0.5, 0.25},
+		{"Cone side", cone, NewPoint(0, -1, -1), 0, 0.5},
+		{"Cone cap", cone, NewPoint(1, -2, 0), 0.75, 0.5},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			u, v := UVAt(tC.object, tC.point)
+
+			if !WithinTolerance(u, tC.u, 1e-9) || !WithinTolerance(v, tC.v, 1e-9) {
+				t.Errorf("Got %v, %v, want %v, %v", u, v, tC.u, tC.v)
+			}
+		})
+	}
+}
+
+func TestUVAtUsesObjectMappingAndTransforms(t *testing.T) {
+	s := NewSphere()
+	s.SetTransform(NewScaling(2, 2, 2))
+
+	g := NewGroup()
+	g.SetTransform(NewTranslation(10, 0, 0))
+	g.AddChild(s)
+
+	if u, v := UVAt(s, NewPoint(12, 0, 0)); !WithinTolerance(u, 0.25, 1e-9) || !WithinTolerance(v, 0.5, 1e-9) {
+		t.Errorf("Got %v, %v, want the natural mapping 0.25, 0.5", u, v)
+	}
+
+	s.SetUVMapping(PlanarMap)
+
+	if u, v := UVAt(s, NewPoint(10.5, 0, -1)); !WithinTolerance(u, 0.25, 1e-9) || !WithinTolerance(v, 0.5, 1e-9) {
+		t.Errorf("Got %v, %v, want the planar mapping 0.25, 0.5", u, v)
+	}
+}
+
+func TestUVCheckerTexture(t *testing.T) {
+	tex := NewUVCheckerTexture(2, 2, black, white)
+	s := NewSphere()
+
+	testCases := []struct {
+		desc  string
+		point Point
+		want  Color
+	}{
+		{"Back bottom", NewPoint(0, -0.7071, -0.7071), black},
+		{"Right top", NewPoint(0.7071, 0.7071, 0), white},
+		{"Left bottom", NewPoint(-0.7071, -0.7071, 0), white},
+		{"Left top", NewPoint(-0.7071, 0.7071, 0), black},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := tex.ColorAt(s, tC.point); !got.Eq(tC.want) {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
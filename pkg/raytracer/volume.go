@@ -0,0 +1,257 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Volume is a heterogeneous medium like a cloud or smoke simulation. Its
+// density grid fills the cube from -1 to 1 in object space and it is moved
+// into place with a transform like every other object. A volume is its own
+// material, rays crossing it are tracked through the grid with delta
+// tracking.
+type Volume struct {
+	*object
+
+	Density      *VoxelGrid
+	DensityScale float64
+	Albedo       Color
+	Anisotropy   float64
+
+	// Emission optionally makes the volume glow with EmissionColor, and
+	// Temperature optionally makes it glow as a black body in kelvin. Both
+	// are radiance per unit density and are scaled by EmissionScale.
+	Emission      *VoxelGrid
+	EmissionColor Color
+	Temperature   *VoxelGrid
+	EmissionScale float64
+
+	maxDensity float64
+}
+
+// NewVolume returns a white, isotropic volume where a density of 1 scatters
+// once per unit distance on average
+func NewVolume(density *VoxelGrid) *Volume {
+	o := newObject()
+
+	v := Volume{
+		object:        &o,
+		Density:       density,
+		DensityScale:  1,
+		Albedo:        NewColor(1, 1, 1),
+		EmissionColor: NewColor(1, 1, 1),
+		EmissionScale: 1,
+	}
+
+	o.parentObject = &v
+	v.Update()
+
+	return &v
+}
+
+// Update recomputes the largest density after the density grid changes
+func (v *Volume) Update() {
+	v.maxDensity = v.Density.Max()
+}
+
+func (v *Volume) SetDensityScale(scale float64) *Volume {
+	v.DensityScale = scale
+
+	return v
+}
+
+func (v *Volume) SetAlbedo(c Color) *Volume {
+	v.Albedo = c
+
+	return v
+}
+
+func (v *Volume) SetAnisotropy(g float64) *Volume {
+	v.Anisotropy = g
+
+	return v
+}
+
+func (v *Volume) SetEmission(grid *VoxelGrid, c Color) *Volume {
+	v.Emission = grid
+	v.EmissionColor = c
+
+	return v
+}
+
+func (v *Volume) SetTemperature(grid *VoxelGrid) *Volume {
+	v.Temperature = grid
+
+	return v
+}
+
+func (v *Volume) SetEmissionScale(scale float64) *Volume {
+	v.EmissionScale = scale
+
+	return v
+}
+
+func (v *Volume) LocalIntersect(objectRay Ray) []Intersection {
+	xtmin, xtmax := checkAxis(objectRay.Origin.X, objectRay.Direction.X)
+	ytmin, ytmax := checkAxis(objectRay.Origin.Y, objectRay.Direction.Y)
+	ztmin, ztmax := checkAxis(objectRay.Origin.Z, objectRay.Direction.Z)
+
+	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
+	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
+
+	if tmin > tmax {
+		return []Intersection{}
+	}
+
+	return []Intersection{
+		NewIntersection(tmin, v),
+		NewIntersection(tmax, v),
+	}
+}
+
+func (v *Volume) LocalNormalAt(objectPoint Point, i Intersection) Vec {
+	return NewCube().LocalNormalAt(objectPoint, i)
+}
+
+func (v *Volume) Bounds() *BoundingBox {
+	return NewBoundingBoxWithValues(NewPoint(-1, -1, -1), NewPoint(1, 1, 1)).Transform(v.GetTransform())
+}
+
+// GetNewMaterial returns the volume itself, so the containers of
+// PrepareComputationsWithHit know rays inside it travel through it
+func (v *Volume) GetNewMaterial() Scatters {
+	return v
+}
+
+func (v *Volume) Emit() Color {
+	return colorBlack
+}
+
+// Scatter lets rays cross the bounding box of the volume unchanged
+func (v *Volume) Scatter(rayIn *Ray, comps *Computations, attenuation *Color, scattered *Ray, source *rand.Rand) bool {
+	*attenuation = NewColor(1, 1, 1)
+	*scattered = NewRay(comps.UnderPoint, rayIn.Direction)
+
+	return true
+}
+
+// DensityAt returns the density per unit distance at a world point
+func (v *Volume) DensityAt(worldPoint Point) float64 {
+	u, vv, w := v.gridCoordinates(worldPoint)
+
+	return v.Density.Sample(u, vv, w) * v.DensityScale
+}
+
+// EmissionAt returns the radiance emitted per unit density at a world point
+func (v *Volume) EmissionAt(worldPoint Point) Color {
+	u, vv, w := v.gridCoordinates(worldPoint)
+	emission := colorBlack
+
+	if v.Emission != nil {
+		emission = emission.Add(v.EmissionColor.MulFloat(v.Emission.Sample(u, vv, w)))
+	}
+
+	if v.Temperature != nil {
+		emission = emission.Add(Blackbody(v.Temperature.Sample(u, vv, w)))
+	}
+
+	return emission.MulFloat(v.EmissionScale)
+}
+
+// SampleCollision uses delta tracking, taking exponential steps with the
+// largest density in the grid and accepting a collision with probability
+// of the real density over that maximum
+func (v *Volume) SampleCollision(r Ray, maxDistance float64, source *rand.Rand) (MediumCollision, bool) {
+	majorant := v.majorant()
+
+	if majorant <= 0 {
+		return MediumCollision{}, false
+	}
+
+	var t float64
+	for {
+		t -= math.Log(1-source.Float64()) / majorant
+
+		if t >= maxDistance {
+			return MediumCollision{}, false
+		}
+
+		point := r.Position(t)
+
+		if source.Float64() < v.DensityAt(point)/majorant {
+			return MediumCollision{
+				Distance: t,
+				Albedo:   v.Albedo,
+				Emission: v.EmissionAt(point),
+			}, true
+		}
+	}
+}
+
+// TransmittanceAlong uses ratio tracking, which takes the same steps as
+// delta tracking but multiplies in the chance of not colliding
+func (v *Volume) TransmittanceAlong(r Ray, distance float64, source *rand.Rand) float64 {
+	majorant := v.majorant()
+
+	if majorant <= 0 {
+		return 1
+	}
+
+	transmittance := 1.0
+
+	var t float64
+	for {
+		t -= math.Log(1-source.Float64()) / majorant
+
+		if t >= distance {
+			return transmittance
+		}
+
+		transmittance *= 1 - v.DensityAt(r.Position(t))/majorant
+
+		if transmittance <= 0 {
+			return 0
+		}
+	}
+}
+
+func (v *Volume) SamplePhase(direction Vec, source *rand.Rand) Vec {
+	return NewMedium(0, v.Albedo).SetAnisotropy(v.Anisotropy).SamplePhase(direction, source)
+}
+
+func (v *Volume) majorant() float64 {
+	return v.maxDensity * v.DensityScale
+}
+
+// gridCoordinates maps a world point to the [0, 1] coordinates of the grids
+func (v *Volume) gridCoordinates(worldPoint Point) (float64, float64, float64) {
+	p := v.WorldToObject(worldPoint)
+
+	return (p.X + 1) / 2, (p.Y + 1) / 2, (p.Z + 1) / 2
+}
+
+// Blackbody returns the radiance of a black body at a temperature in kelvin
+// for red, green and blue wavelengths, relative to the green radiance of a
+// 6500K black body
+func Blackbody(kelvin float64) Color {
+	if kelvin <= 0 {
+		return colorBlack
+	}
+
+	reference := planck(0.55, 6500)
+
+	return NewColor(
+		planck(0.65, kelvin)/reference,
+		planck(0.55, kelvin)/reference,
+		planck(0.45, kelvin)/reference,
+	)
+}
+
+// planck is Planck's law for a wavelength in micrometers, without the
+// constant factor
+func planck(lambda, kelvin float64) float64 {
+	// Second radiation constant hc/k in micrometer kelvin
+	const c2 = 14387.77
+
+	return 1 / (math.Pow(lambda, 5) * (math.Exp(c2/(lambda*kelvin)) - 1))
+}
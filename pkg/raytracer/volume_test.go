@@ -0,0 +1,135 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func constantVoxelGrid(v float64) *VoxelGrid {
+	g := NewVoxelGrid(2, 2, 2)
+
+	for i := range g.Values {
+		g.Values[i] = v
+	}
+
+	return g
+}
+
+func TestVolumeDensityFollowsTransform(t *testing.T) {
+	g := NewVoxelGrid(2, 1, 1)
+	g.Set(1, 0, 0, 1)
+
+	v := NewVolume(g).SetDensityScale(3)
+	v.SetTransform(NewTranslation(10, 0, 0))
+
+	testCases := []struct {
+		desc  string
+		point Point
+		want  float64
+	}{
+		{desc: "Empty half", point: NewPoint(9.5, 0, 0), want: 0},
+		{desc: "Dense half", point: NewPoint(10.5, 0, 0), want: 3},
+		{desc: "Outside", point: NewPoint(0, 0, 0), want: 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := v.DensityAt(tC.point)
+
+			if math.Abs(got-tC.want) > 1e-9 {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestVolumeIsItsOwnMedium(t *testing.T) {
+	v := NewVolume(constantVoxelGrid(1))
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+	xs := v.Intersect(r)
+
+	if len(xs) != 2 || xs[0].Time != 4 || xs[1].Time != 6 {
+		t.Fatalf("Got %v, want hits at 4 and 6", xs)
+	}
+
+	comps := PrepareComputationsWithHit(xs[1], r, xs)
+
+	if comps.Enclosing != v {
+		t.Errorf("Got %v, want the volume", comps.Enclosing)
+	}
+}
+
+func TestVolumeTrackingMatchesConstantMedium(t *testing.T) {
+	w := NewWorld()
+	w.Source = rand.New(rand.NewSource(1))
+
+	// Half the grid is empty and the density ramps down between the voxel
+	// centers, so the optical depth along a ray through the middle is 1
+	g := NewVoxelGrid(1, 1, 4)
+	g.Set(0, 0, 0, 1).Set(0, 0, 1, 1)
+
+	v := NewVolume(g)
+	w.AddObject(v)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	var transmittance float64
+	var escaped int
+	n := 20000
+
+	for i := 0; i < n; i++ {
+		transmittance += w.Transmittance(r)
+
+		unitRay := NewRay(NewPoint(0, 0, -1), NewVec(0, 0, 1))
+		if _, collides := v.SampleCollision(unitRay, 2, w.Source); !collides {
+			escaped++
+		}
+	}
+
+	want := math.Exp(-1)
+
+	if got := transmittance / float64(n); math.Abs(got-want) > 0.02 {
+		t.Errorf("Got transmittance %v, want %v", got, want)
+	}
+
+	if got := float64(escaped) / float64(n); math.Abs(got-want) > 0.02 {
+		t.Errorf("Got escape rate %v, want %v", got, want)
+	}
+}
+
+func TestPathTracerEmissiveVolume(t *testing.T) {
+	w := NewWorld()
+	w.Source = rand.New(rand.NewSource(1))
+
+	// A black, very dense volume absorbs everything and shows its emission
+	v := NewVolume(constantVoxelGrid(1)).SetDensityScale(50).SetAlbedo(NewColor(0, 0, 0))
+	v.SetEmission(constantVoxelGrid(1), NewColor(1, 0.5, 0.25))
+	w.AddObject(v)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	got := NewPathTracer().ColorAt(w, &r, 4)
+
+	if !got.Eq(NewColor(1, 0.5, 0.25)) {
+		t.Errorf("Got %v, want %v", got, NewColor(1, 0.5, 0.25))
+	}
+}
+
+func TestBlackbody(t *testing.T) {
+	hot := Blackbody(6500)
+
+	if math.Abs(hot.G-1) > 1e-9 {
+		t.Errorf("Got %v, want green of 1", hot)
+	}
+
+	fire := Blackbody(1500)
+
+	if fire.R <= fire.G || fire.G <= fire.B {
+		t.Errorf("Got %v, want a red glow", fire)
+	}
+
+	if got := Blackbody(0); !got.Eq(NewColor(0, 0, 0)) {
+		t.Errorf("Got %v, want %v", got, NewColor(0, 0, 0))
+	}
+}
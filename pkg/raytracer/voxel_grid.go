@@ -0,0 +1,290 @@
+package raytracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// voxelMagic starts binary voxel files
+const voxelMagic = "VOXB"
+
+// VoxelGrid is a 3D grid of values, stored with x changing fastest and then
+// y and z. Values are sampled at voxel centers.
+type VoxelGrid struct {
+	Width  int
+	Height int
+	Depth  int
+	Values []float64
+}
+
+func NewVoxelGrid(width, height, depth int) *VoxelGrid {
+	return &VoxelGrid{
+		Width:  width,
+		Height: height,
+		Depth:  depth,
+		Values: make([]float64, width*height*depth),
+	}
+}
+
+// LoadVoxelGrid reads a voxel file, see ReadVoxelGrid for the formats
+func LoadVoxelGrid(filename string) (*VoxelGrid, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadVoxelGrid(f)
+}
+
+// ReadVoxelGrid reads a grid in either the binary format, which is "VOXB"
+// followed by the width, height and depth as little endian uint32 and the
+// values as little endian float32, or the text format, which is "voxels"
+// followed by the width, height, depth and values separated by whitespace.
+// Lines starting with # in text files are comments.
+func ReadVoxelGrid(r io.Reader) (*VoxelGrid, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(voxelMagic))
+	if err == nil && bytes.Equal(magic, []byte(voxelMagic)) {
+		return readBinaryVoxelGrid(br)
+	}
+
+	return readTextVoxelGrid(br)
+}
+
+func readBinaryVoxelGrid(r io.Reader) (*VoxelGrid, error) {
+	var header struct {
+		Magic  [4]byte
+		Width  uint32
+		Height uint32
+		Depth  uint32
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading voxel header: %w", err)
+	}
+
+	count, err := voxelCount(int(header.Width), int(header.Height), int(header.Depth))
+	if err != nil {
+		return nil, err
+	}
+
+	// Read in chunks so a header promising more values than the file holds
+	// fails at the end of the data instead of allocating for all of them
+	var values []float64
+	chunk := make([]float32, voxelChunk)
+
+	for len(values) < count {
+		n := count - len(values)
+		if n > voxelChunk {
+			n = voxelChunk
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, chunk[:n]); err != nil {
+			return nil, fmt.Errorf("reading voxel values: %w", err)
+		}
+
+		for _, v := range chunk[:n] {
+			values = append(values, float64(v))
+		}
+	}
+
+	return &VoxelGrid{
+		Width:  int(header.Width),
+		Height: int(header.Height),
+		Depth:  int(header.Depth),
+		Values: values,
+	}, nil
+}
+
+// voxelChunk is how many values binary files are read at a time
+const voxelChunk = 1 << 16
+
+// voxelCount returns the number of voxels in a grid of the given size, which
+// must be positive and hold at most math.MaxInt32 voxels
+func voxelCount(width, height, depth int) (int, error) {
+	count := 1
+
+	for _, n := range []int{width, height, depth} {
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid voxel grid size %dx%dx%d", width, height, depth)
+		}
+
+		if count > math.MaxInt32/n {
+			return 0, fmt.Errorf("voxel grid %dx%dx%d is too large", width, height, depth)
+		}
+
+		count *= n
+	}
+
+	return count, nil
+}
+
+func readTextVoxelGrid(r io.Reader) (*VoxelGrid, error) {
+	scanner := bufio.NewScanner(r)
+
+	var fields []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields = append(fields, strings.Fields(line)...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(fields) < 4 || fields[0] != "voxels" {
+		return nil, fmt.Errorf("not a voxel file")
+	}
+
+	var size [3]int
+	for i := range size {
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid voxel grid size %q", fields[i+1])
+		}
+
+		size[i] = n
+	}
+
+	count, err := voxelCount(size[0], size[1], size[2])
+	if err != nil {
+		return nil, err
+	}
+
+	values := fields[4:]
+
+	if len(values) != count {
+		return nil, fmt.Errorf("got %d voxel values, want %d", len(values), count)
+	}
+
+	g := NewVoxelGrid(size[0], size[1], size[2])
+
+	for i, field := range values {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid voxel value %q", field)
+		}
+
+		g.Values[i] = v
+	}
+
+	return g, nil
+}
+
+// Save writes the grid in the binary format
+func (g *VoxelGrid) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := g.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Write writes the grid in the binary format
+func (g *VoxelGrid) Write(w io.Writer) error {
+	header := []interface{}{
+		[]byte(voxelMagic),
+		uint32(g.Width),
+		uint32(g.Height),
+		uint32(g.Depth),
+	}
+
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	values := make([]float32, len(g.Values))
+	for i, v := range g.Values {
+		values[i] = float32(v)
+	}
+
+	return binary.Write(w, binary.LittleEndian, values)
+}
+
+func (g *VoxelGrid) Get(x, y, z int) float64 {
+	return g.Values[(z*g.Height+y)*g.Width+x]
+}
+
+func (g *VoxelGrid) Set(x, y, z int, v float64) *VoxelGrid {
+	g.Values[(z*g.Height+y)*g.Width+x] = v
+
+	return g
+}
+
+// Max returns the largest value in the grid
+func (g *VoxelGrid) Max() float64 {
+	max := 0.0
+
+	for _, v := range g.Values {
+		max = math.Max(max, v)
+	}
+
+	return max
+}
+
+// Sample interpolates the grid trilinearly at u, v, w in [0, 1]. Points
+// outside the grid are zero.
+func (g *VoxelGrid) Sample(u, v, w float64) float64 {
+	if u < 0 || u > 1 || v < 0 || v > 1 || w < 0 || w > 1 {
+		return 0
+	}
+
+	x, fx := voxelCoordinate(u, g.Width)
+	y, fy := voxelCoordinate(v, g.Height)
+	z, fz := voxelCoordinate(w, g.Depth)
+
+	x1 := clampIndex(x+1, g.Width)
+	y1 := clampIndex(y+1, g.Height)
+	z1 := clampIndex(z+1, g.Depth)
+
+	c00 := lerp(g.Get(x, y, z), g.Get(x1, y, z), fx)
+	c10 := lerp(g.Get(x, y1, z), g.Get(x1, y1, z), fx)
+	c01 := lerp(g.Get(x, y, z1), g.Get(x1, y, z1), fx)
+	c11 := lerp(g.Get(x, y1, z1), g.Get(x1, y1, z1), fx)
+
+	return lerp(lerp(c00, c10, fy), lerp(c01, c11, fy), fz)
+}
+
+// voxelCoordinate returns the voxel before the coordinate and how far the
+// coordinate is towards the next one, measured between voxel centers
+func voxelCoordinate(t float64, size int) (int, float64) {
+	p := t*float64(size) - 0.5
+
+	if p <= 0 {
+		return 0, 0
+	}
+
+	i := int(p)
+
+	if i >= size-1 {
+		return size - 1, 0
+	}
+
+	return i, p - float64(i)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
@@ -0,0 +1,100 @@
+package raytracer
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadTextVoxelGrid(t *testing.T) {
+	input := `voxels 2 1 2
+# x changes fastest, then y, then z
+0 1
+2 3
+`
+
+	g, err := ReadVoxelGrid(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Width != 2 || g.Height != 1 || g.Depth != 2 {
+		t.Fatalf("Got %vx%vx%v, want 2x1x2", g.Width, g.Height, g.Depth)
+	}
+
+	if got := g.Get(1, 0, 1); got != 3 {
+		t.Errorf("Got %v, want %v", got, 3)
+	}
+}
+
+func TestReadInvalidVoxelGrid(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+	}{
+		{desc: "Not a voxel file", input: "P3 1 1 255"},
+		{desc: "Invalid size", input: "voxels 2 x 1 0 0"},
+		{desc: "Missing values", input: "voxels 2 1 1 0"},
+		{desc: "Invalid value", input: "voxels 1 1 1 zero"},
+		{desc: "Truncated binary", input: "VOXB\x02\x00\x00\x00"},
+		{desc: "Empty binary grid", input: "VOXB\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"},
+		{desc: "Huge binary grid", input: "VOXB\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff"},
+		{desc: "Binary grid larger than its data", input: "VOXB\x00\x04\x00\x00\x00\x04\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00"},
+		{desc: "Huge text grid", input: "voxels 100000000 100000000 100000000 0"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := ReadVoxelGrid(strings.NewReader(tC.input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestBinaryVoxelGridRoundTrip(t *testing.T) {
+	g := NewVoxelGrid(3, 2, 1)
+	g.Set(0, 0, 0, 0.25).Set(2, 1, 0, 4)
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadVoxelGrid(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range g.Values {
+		if got.Values[i] != g.Values[i] {
+			t.Errorf("Value %d, got %v, want %v", i, got.Values[i], g.Values[i])
+		}
+	}
+}
+
+func TestVoxelGridSample(t *testing.T) {
+	g := NewVoxelGrid(2, 2, 2)
+	g.Set(1, 0, 0, 1).Set(1, 1, 0, 1).Set(1, 0, 1, 1).Set(1, 1, 1, 1)
+
+	testCases := []struct {
+		desc    string
+		u, v, w float64
+		want    float64
+	}{
+		{desc: "First voxel center", u: 0.25, v: 0.25, w: 0.25, want: 0},
+		{desc: "Second voxel center", u: 0.75, v: 0.25, w: 0.75, want: 1},
+		{desc: "Halfway between centers", u: 0.5, v: 0.5, w: 0.5, want: 0.5},
+		{desc: "Clamped at the edge", u: 1, v: 0, w: 0, want: 1},
+		{desc: "Outside", u: 1.5, v: 0.5, w: 0.5, want: 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := g.Sample(tC.u, tC.v, tC.w)
+
+			if math.Abs(got-tC.want) > 1e-9 {
+				t.Errorf("Got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
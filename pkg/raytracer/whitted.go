@@ -0,0 +1,141 @@
+package raytracer
+
+import (
+	"math"
+)
+
+// Whitted is the classic recursive ray tracer from the book. It shades hits
+// with the Phong model for every light in the world and follows perfect
+// reflection and refraction rays, which makes it a fast preview integrator.
+type Whitted struct{}
+
+func NewWhitted() *Whitted {
+	return &Whitted{}
+}
+
+func (wh *Whitted) ColorAt(w *World, r *Ray, remaining int) Color {
+	color, _ := wh.colorAt(w, r, remaining, true)
+
+	return color
+}
+
+// ColorAndCoverageAt is ColorAt for a transparent camera, where every camera
+// ray that hits an object covers the pixel
+func (wh *Whitted) ColorAndCoverageAt(w *World, r *Ray, remaining int) (Color, float64) {
+	color, escaped := wh.colorAt(w, r, remaining, true)
+
+	if escaped {
+		return colorBlack, 0
+	}
+
+	return color, 1
+}
+
+// colorAt traces a ray and reports whether a primary ray missed everything
+func (wh *Whitted) colorAt(w *World, r *Ray, remaining int, primary bool) (Color, bool) {
+	xs := w.Intersect(*r)
+	hit, didHit := GetHit(xs)
+
+	if !didHit {
+		if primary {
+			return w.CameraMissColor(r), true
+		}
+
+		return w.MissColor(r), false
+	}
+
+	comps := PrepareComputationsWithHit(hit, *r, xs)
+
+	return Absorption(r, comps).Mul(wh.ShadeHit(w, comps, remaining)), false
+}
+
+func (wh *Whitted) ShadeHit(w *World, comps *Computations, remaining int) Color {
+	object := *comps.Object
+	material := phongMaterial(object.GetNewMaterial())
+
+	color := object.GetNewMaterial().Emit()
+
+	for _, light := range w.Lights {
+		inShadow := w.IsShadowed(*light, comps.OverPoint)
+
+		c := material.Lighting(object, *light, comps.OverPoint, comps.Eyev, comps.Normalv, inShadow)
+		color = color.Add(c)
+	}
+
+	reflected := wh.ReflectedColor(w, comps, remaining)
+	refracted := wh.RefractedColor(w, comps, remaining)
+
+	if material.Reflectivity > 0 && material.Transparency > 0 {
+		reflectance := Schlick(comps)
+
+		return color.Add(reflected.MulFloat(reflectance)).Add(refracted.MulFloat(1 - reflectance))
+	}
+
+	return color.Add(reflected).Add(refracted)
+}
+
+func (wh *Whitted) ReflectedColor(w *World, comps *Computations, remaining int) Color {
+	if remaining <= 0 {
+		return colorBlack
+	}
+
+	material := phongMaterial((*comps.Object).GetNewMaterial())
+
+	if material.Reflectivity == 0 {
+		return colorBlack
+	}
+
+	reflectRay := NewRay(comps.OverPoint, comps.Reflectv)
+	color, _ := wh.colorAt(w, &reflectRay, remaining-1, false)
+
+	return color.MulFloat(material.ParameterAt(ReflectiveParameter, *comps.Object, comps.Point))
+}
+
+func (wh *Whitted) RefractedColor(w *World, comps *Computations, remaining int) Color {
+	if remaining <= 0 {
+		return colorBlack
+	}
+
+	material := phongMaterial((*comps.Object).GetNewMaterial())
+
+	if material.Transparency == 0 {
+		return colorBlack
+	}
+
+	nRatio := comps.N1 / comps.N2
+	cosI := comps.Eyev.Dot(comps.Normalv)
+	sin2T := nRatio * nRatio * (1 - cosI*cosI)
+
+	if sin2T > 1 {
+		return colorBlack
+	}
+
+	cosT := math.Sqrt(1.0 - sin2T)
+
+	direction := comps.Normalv.Mul(nRatio*cosI - cosT).Sub(comps.Eyev.Mul(nRatio))
+
+	refractRay := NewRay(comps.UnderPoint, direction)
+
+	color, _ := wh.colorAt(w, &refractRay, remaining-1, false)
+
+	return color.MulFloat(material.ParameterAt(TransparencyParameter, *comps.Object, comps.Point))
+}
+
+// phongMaterial returns the Phong parameters used to preview a material.
+// Path tracing materials are mapped to the closest Phong equivalent.
+func phongMaterial(s Scatters) *Material {
+	switch m := s.(type) {
+	case *Material:
+		return m
+	case *Diffuse:
+		return NewMaterial().SetColor(m.Albedo).SetTexture(m.Texture)
+	case *Metal:
+		return NewMaterial().SetColor(m.Albedo).SetTexture(m.Texture).SetDiffuse(0.3).SetReflective(math.Max(1-m.Fuzziness, 0))
+	case *Dielectric:
+		return NewMaterial().SetColor(colorBlack).SetAmbient(0).SetDiffuse(0).SetShininess(300).SetReflective(1).SetTransparency(1).SetRefractiveIndex(m.IndexOfRefraction)
+	case Participating:
+		return NewMaterial().SetColor(colorBlack).SetAmbient(0).SetDiffuse(0).SetSpecular(0).SetTransparency(1)
+	default:
+		return NewMaterial().SetColor(colorBlack).SetAmbient(0).SetDiffuse(0).SetSpecular(0)
+	}
+}
@@ -0,0 +1,136 @@
+package raytracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWhittedShadeHitWithMultipleLights(t *testing.T) {
+	w := NewDefaultWorld()
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	s := w.Objects[0]
+	i := NewIntersection(4, *s)
+	comps := PrepareComputations(i, r)
+
+	single := NewWhitted().ShadeHit(w, &comps, 4)
+
+	w.AddLight(NewPointLight(NewPoint(-10, 10, -10), NewColor(1, 1, 1)))
+
+	got := NewWhitted().ShadeHit(w, &comps, 4)
+	want := single.MulFloat(2)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestWhittedAddsEmission(t *testing.T) {
+	w := NewWorld()
+	s := NewSphere()
+	s.SetNewMaterial(NewEmissive(NewColor(2, 2, 2)))
+	w.AddObject(s)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
+
+	got := NewWhitted().ColorAt(w, &r, 4)
+	want := NewColor(2, 2, 2)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestWhittedAbsorbsInsideGlass(t *testing.T) {
+	w := NewWorld()
+
+	glass := NewSphere()
+	glass.SetTransform(NewScaling(2, 2, 2))
+	glass.SetNewMaterial(NewDielectric(1.5).SetAbsorption(NewColor(1, 0, 0)))
+	w.AddObject(glass)
+
+	light := NewSphere()
+	light.SetTransform(NewScaling(0.5, 0.5, 0.5))
+	light.SetNewMaterial(NewEmissive(NewColor(2, 2, 2)))
+	w.AddObject(light)
+
+	r := NewRay(NewPoint(0, 0, -1.5), NewVec(0, 0, 1))
+
+	got := NewWhitted().ColorAt(w, &r, 4)
+	want := NewColor(2*math.Exp(-1), 2, 2)
+
+	if !got.Eq(want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestPhongMaterialForPathTracingMaterials(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		material Scatters
+		color    Color
+		reflect  float64
+		transp   float64
+		ri       float64
+	}{
+		{
+			desc:     "Diffuse",
+			material: NewDiffuse(NewColor(0.5, 0.2, 0.1)),
+			color:    NewColor(0.5, 0.2, 0.1),
+			ri:       1,
+		},
+		{
+			desc:     "Metal",
+			material: NewMetal(NewColor(0.8, 0.8, 0.8), 0.25),
+			color:    NewColor(0.8, 0.8, 0.8),
+			reflect:  0.75,
+			ri:       1,
+		},
+		{
+			desc:     "Dielectric",
+			material: NewDielectric(1.5),
+			color:    NewColor(0, 0, 0),
+			reflect:  1,
+			transp:   1,
+			ri:       1.5,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m := phongMaterial(tC.material)
+
+			if !m.Color.Eq(tC.color) {
+				t.Errorf("Invalid color, got %v, want %v", m.Color, tC.color)
+			}
+			if m.Reflectivity != tC.reflect {
+				t.Errorf("Invalid reflectivity, got %v, want %v", m.Reflectivity, tC.reflect)
+			}
+			if m.Transparency != tC.transp {
+				t.Errorf("Invalid transparency, got %v, want %v", m.Transparency, tC.transp)
+			}
+			if m.RefractiveIndex != tC.ri {
+				t.Errorf("Invalid refractive index, got %v, want %v", m.RefractiveIndex, tC.ri)
+			}
+		})
+	}
+}
+
+func TestMaterialScatterUsesPatternColor(t *testing.T) {
+	s := NewSphere()
+	m := NewMaterial().SetPattern(NewStripePattern(white, black))
+	s.SetNewMaterial(m)
+
+	r := NewRay(NewPoint(-5, 0, 0), NewVec(1, 0, 0))
+	comps := PrepareComputations(NewIntersection(4, s), r)
+
+	var attenuation Color
+	var scattered Ray
+
+	if !m.Scatter(&r, &comps, &attenuation, &scattered, NewWorld().Source) {
+		t.Fatal("Expected material to scatter")
+	}
+
+	if !attenuation.Eq(black) {
+		t.Errorf("Got %v, want %v", attenuation, black)
+	}
+}
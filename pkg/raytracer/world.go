@@ -21,8 +21,16 @@ func (is IntersectonSorter) Swap(i, j int) {
 type World struct {
 	Objects    []*Intersectable
 	Lights     []*Light
-	Background *Color
-	Source     *rand.Rand
+	Background Background
+	// HideBackground makes the background black to camera rays while it
+	// still lights the scene through reflections and bounces
+	HideBackground bool
+	// Fog fills the space outside all objects inside FogBounds with a
+	// participating medium. Rays that leave the bounds escape to the
+	// background.
+	Fog       *Medium
+	FogBounds *BoundingBox
+	Source    *rand.Rand
 }
 
 func NewWorld() *World {
@@ -33,18 +41,18 @@ func NewWorld() *World {
 
 func NewDefaultWorld() *World {
 	s1 := NewSphere()
-	m1 := NewDiffuse(NewColor(0.8, 1.0, 0.6))
+	m1 := NewMaterial().SetColor(NewColor(0.8, 1.0, 0.6)).SetDiffuse(0.7).SetSpecular(0.2)
 	s1.SetNewMaterial(m1)
 
-	s2 := NewSphere().SetTransform(NewScaling(0.5, 0.5, 0.5))
+	s2 := NewSphere()
+	s2.SetNewMaterial(NewMaterial())
+	s2.SetTransform(NewScaling(0.5, 0.5, 0.5))
 
 	w := NewWorld()
+	w.AddLight(NewPointLight(NewPoint(-10, 10, -10), NewColor(1, 1, 1)))
 	w.AddObject(s1)
 	w.AddObject(s2)
 
-	c := NewColor(0.1, 0.1, 0.1)
-	w.Background = &c
-
 	return w
 }
 
@@ -73,74 +81,61 @@ func (w *World) Intersect(r Ray) []Intersection {
 	return xs
 }
 
-// func (w *World) ShadeHit(comps Computations, remaining int) Color {
-// 	color := NewColor(0, 0, 0)
-
-// 	for _, light := range w.Lights {
-// 		inShadow := w.IsShadowed(*light, comps.OverPoint)
-
-// 		c2 := (*comps.Object).GetMaterial().Lighting(*comps.Object, *light, comps.OverPoint, comps.Eyev, comps.Normalv, inShadow)
-// 		color = color.Add(c2)
-// 	}
-
-// 	reflected := w.ReflectedColor(comps, remaining)
-// 	refracted := w.RefractedColor(comps, remaining)
+var colorBlack Color = Color{0, 0, 0}
 
-// 	material := (*comps.Object).GetMaterial()
+func (w *World) ColorAt(r *Ray, remaining int) Color {
+	return NewPathTracer().ColorAt(w, r, remaining)
+}
 
-// 	if material.Reflectivity > 0 && material.Transparency > 0 {
-// 		reflectance := Schlick(comps)
+// SetSky lights the world with a procedural sky. The sky is the background
+// for missed rays and the sun is added as a light for Phong shading.
+func (w *World) SetSky(s *Sky) *World {
+	w.Background = s
+	w.AddLight(s.SunLight())
 
-// 		return color.Add(reflected.MulFloat(reflectance)).Add(refracted.MulFloat(1 - reflectance))
-// 	}
+	return w
+}
 
-// 	return color.Add(reflected).Add(refracted)
-// }
+// SetFog fills the part of the world inside bounds with an atmospheric
+// medium
+func (w *World) SetFog(m *Medium, bounds *BoundingBox) *World {
+	w.Fog = m
+	w.FogBounds = bounds
 
-var colorBlack Color = Color{0, 0, 0}
+	return w
+}
 
-func (w *World) ColorAt(r *Ray, remaining int) Color {
-	if remaining <= 0 {
+// MissColor returns the radiance along a ray that hits nothing
+func (w *World) MissColor(r *Ray) Color {
+	if w.Background == nil {
 		return colorBlack
 	}
 
-	xs := w.Intersect(*r)
-	hit, didHit := GetHit(xs)
-
-	if !didHit {
-		if w.Background != nil {
-			return *w.Background
-		}
+	return w.Background.ColorAt(r.Direction)
+}
 
+// CameraMissColor is MissColor for rays coming straight from the camera
+func (w *World) CameraMissColor(r *Ray) Color {
+	if w.HideBackground {
 		return colorBlack
 	}
 
-	var scattered Ray
-	var attenuation Color
-
-	comps := PrepareComputationsWithHit(hit, *r, xs)
-	object := *comps.Object
-
-	emit := object.GetNewMaterial().Emit()
-
-	if !object.GetNewMaterial().Scatter(r, comps, &attenuation, &scattered, w.Source) {
-		return emit
-	}
-
-	return emit.Add(attenuation.Mul(w.ColorAt(&scattered, remaining-1)))
-
-	// unitDirection := r.Direction.Norm()
-	// t := 0.5 * (unitDirection.Y + 1.0)
+	return w.MissColor(r)
+}
 
-	// return (NewColor(1, 1, 1).MulFloat(1 - t)).Add(NewColor(0.5, 0.7, 1.0).MulFloat(t))
+// SetBackground sets the background and whether camera rays can see it
+func (w *World) SetBackground(b Background, hidden bool) *World {
+	w.Background = b
+	w.HideBackground = hidden
 
-	// if !didHit {
-	// 	return NewColor(0, 0, 0)
-	// }
+	return w
+}
 
-	// comps := PrepareComputationsWithHit(hit, r, xs)
+// environment returns the background when it can be sampled as a light
+func (w *World) environment() (Environment, bool) {
+	e, ok := w.Background.(Environment)
 
-	// return w.ShadeHit(comps, remaining)
+	return e, ok
 }
 
 func (w *World) IsShadowed(l Light, p Point) bool {
@@ -159,46 +154,3 @@ func (w *World) IsShadowed(l Light, p Point) bool {
 
 	return false
 }
-
-// func (w *World) ReflectedColor(comps Computations, remaining int) Color {
-// 	if remaining <= 0 {
-// 		return NewColor(0, 0, 0)
-// 	}
-
-// 	if (*comps.Object).GetMaterial().Reflectivity == 0 {
-// 		return Color{0, 0, 0}
-// 	}
-
-// 	reflectRay := NewRay(comps.OverPoint, comps.Reflectv)
-// 	color := w.ColorAt(reflectRay, remaining-1)
-
-// 	return color.MulFloat((*comps.Object).GetMaterial().Reflectivity)
-// }
-
-// func (w *World) RefractedColor(comps Computations, remaining int) Color {
-// 	if remaining <= 0 {
-// 		return NewColor(0, 0, 0)
-// 	}
-
-// 	if (*comps.Object).GetMaterial().Transparency == 0 {
-// 		return NewColor(0, 0, 0)
-// 	}
-
-// 	nRatio := comps.N1 / comps.N2
-// 	cosI := comps.Eyev.Dot(comps.Normalv)
-// 	sin2T := nRatio * nRatio * (1 - cosI*cosI)
-
-// 	if sin2T > 1 {
-// 		return NewColor(0, 0, 0)
-// 	}
-
-// 	cosT := math.Sqrt(1.0 - sin2T)
-
-// 	direction := comps.Normalv.Mul(nRatio*cosI - cosT).Sub(comps.Eyev.Mul(nRatio))
-
-// 	refractRay := NewRay(comps.UnderPoint, direction)
-
-// 	color := w.ColorAt(refractRay, remaining-1).MulFloat((*comps.Object).GetMaterial().Transparency)
-
-// 	return color
-// }
@@ -62,7 +62,7 @@ func TestShadeIntersection(t *testing.T) {
 
 	comps := PrepareComputations(i, r)
 
-	c := w.ShadeHit(comps, 4)
+	c := NewWhitted().ShadeHit(w, &comps, 4)
 
 	want := NewColor(0.380661, 0.475826, 0.285495)
 
@@ -84,7 +84,7 @@ func TestShadeIntersectionFromInside(t *testing.T) {
 
 	comps := PrepareComputations(i, r)
 
-	c := w.ShadeHit(comps, 4)
+	c := NewWhitted().ShadeHit(w, &comps, 4)
 
 	want := NewColor(0.90498, 0.90498, 0.90498)
 
@@ -98,7 +98,7 @@ func TestWhenRayMisses(t *testing.T) {
 	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 1, 0))
 
 	want := NewColor(0, 0, 0)
-	got := w.ColorAt(r, 4)
+	got := NewWhitted().ColorAt(w, &r, 4)
 
 	if !got.Eq(want) {
 		t.Errorf("Did not get black, got: %v", got)
@@ -110,7 +110,7 @@ func TestWhenRayHits(t *testing.T) {
 	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
 
 	want := NewColor(0.380661, 0.475826, 0.285495)
-	got := w.ColorAt(r, 4)
+	got := NewWhitted().ColorAt(w, &r, 4)
 
 	if !got.Eq(want) {
 		t.Errorf("Did not get correct color, got: %v, want %v", got, want)
@@ -120,14 +120,14 @@ func TestWhenRayHits(t *testing.T) {
 func TestUsesHitToComputeColor(t *testing.T) {
 	w := NewDefaultWorld()
 	outer := *w.Objects[0]
-	outer.GetMaterial().SetAmbient(1)
+	outer.GetNewMaterial().(*Material).SetAmbient(1)
 	inner := *w.Objects[1]
-	inner.GetMaterial().SetAmbient(1)
+	inner.GetNewMaterial().(*Material).SetAmbient(1)
 
 	r := NewRay(NewPoint(0, 0, 0.75), NewVec(0, 0, -1))
 
-	want := inner.GetMaterial().Color
-	got := w.ColorAt(r, 4)
+	want := inner.GetNewMaterial().(*Material).Color
+	got := NewWhitted().ColorAt(w, &r, 4)
 
 	if !got.Eq(want) {
 		t.Errorf("Did not get correct color, got: %v, want %v", got, want)
@@ -141,7 +141,7 @@ func TestShadeInShadow(t *testing.T) {
 	s1 := NewSphere()
 	w.AddObject(s1)
 
-	s2 := NewSphere().SetTransform(NewTranslation(0, 0, 10))
+	s2 := NewSphere().SetNewMaterial(NewMaterial()).SetTransform(NewTranslation(0, 0, 10))
 	w.AddObject(s1)
 
 	r := NewRay(NewPoint(0, 0, 5), NewVec(0, 0, 1))
@@ -149,7 +149,7 @@ func TestShadeInShadow(t *testing.T) {
 
 	comps := PrepareComputations(i, r)
 
-	got := w.ShadeHit(comps, 4)
+	got := NewWhitted().ShadeHit(w, &comps, 4)
 	want := NewColor(0.1, 0.1, 0.1)
 
 	if !got.Eq(want) {
@@ -161,11 +161,11 @@ func TestReflectedColor(t *testing.T) {
 	w := NewDefaultWorld()
 	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 0, 1))
 	s := *w.Objects[1]
-	s.GetMaterial().SetAmbient(1)
+	s.GetNewMaterial().(*Material).SetAmbient(1)
 	i := NewIntersection(1, s)
 
 	comps := PrepareComputations(i, r)
-	color := w.ReflectedColor(comps, 4)
+	color := NewWhitted().ReflectedColor(w, &comps, 4)
 
 	if !color.Eq(NewColor(0, 0, 0)) {
 		t.Errorf("Invalid color, got %v, want %v", color, NewColor(0, 0, 0))
@@ -174,14 +174,14 @@ func TestReflectedColor(t *testing.T) {
 
 func TestReflectedColorForAReflectiveMaterial(t *testing.T) {
 	w := NewDefaultWorld()
-	s := NewPlane().SetMaterial(NewMaterial().SetReflective(0.5)).SetTransform(NewTranslation(0, -1, 0))
+	s := NewPlane().SetNewMaterial(NewMaterial().SetReflective(0.5)).SetTransform(NewTranslation(0, -1, 0))
 	w.AddObject(s)
 
 	r := NewRay(NewPoint(0, 0, -3), NewVec(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
 	i := NewIntersection(math.Sqrt(2), s)
 
 	comps := PrepareComputations(i, r)
-	color := w.ShadeHit(comps, 4)
+	color := NewWhitted().ShadeHit(w, &comps, 4)
 
 	want := NewColor(0.876756, 0.924339, 0.829173)
 
@@ -194,14 +194,14 @@ func TestMutuallyReflectiveSurfaces(t *testing.T) {
 	w := NewWorld()
 	w.AddLight(NewPointLight(NewPoint(0, 0, 0), NewColor(1, 1, 1)))
 
-	lower := NewPlane().SetMaterial(NewMaterial().SetReflective(1)).SetTransform(NewTranslation(0, -1, 0))
-	upper := NewPlane().SetMaterial(NewMaterial().SetReflective(1)).SetTransform(NewTranslation(0, 1, 0))
+	lower := NewPlane().SetNewMaterial(NewMaterial().SetReflective(1)).SetTransform(NewTranslation(0, -1, 0))
+	upper := NewPlane().SetNewMaterial(NewMaterial().SetReflective(1)).SetTransform(NewTranslation(0, 1, 0))
 	w.AddObject(lower)
 	w.AddObject(upper)
 
 	r := NewRay(NewPoint(0, 0, 0), NewVec(0, 1, 0))
 
-	color := w.ColorAt(r, 4)
+	color := NewWhitted().ColorAt(w, &r, 4)
 
 	if !color.Eq(NewColor(9.5, 9.5, 9.5)) {
 		t.Errorf("Got %v, want %v", color, NewColor(9.5, 9.5, 9.5))
@@ -211,7 +211,7 @@ func TestMutuallyReflectiveSurfaces(t *testing.T) {
 func TestReflectedColorAtMaximumRecursiveDepth(t *testing.T) {
 	w := NewDefaultWorld()
 
-	s := NewPlane().SetMaterial(NewMaterial().SetReflective(0.5)).SetTransform(NewTranslation(0, -1, 0))
+	s := NewPlane().SetNewMaterial(NewMaterial().SetReflective(0.5)).SetTransform(NewTranslation(0, -1, 0))
 	w.AddObject(s)
 
 	r := NewRay(NewPoint(0, 0, -3), NewVec(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
@@ -219,7 +219,7 @@ func TestReflectedColorAtMaximumRecursiveDepth(t *testing.T) {
 
 	comps := PrepareComputations(i, r)
 
-	color := w.ReflectedColor(comps, 0)
+	color := NewWhitted().ReflectedColor(w, &comps, 0)
 
 	if !color.Eq(NewColor(0, 0, 0)) {
 		t.Errorf("Got %v, want %v", color, NewColor(0, 0, 0))
@@ -240,7 +240,7 @@ func TestFindRefractedColorOfOpaqueObject(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[0], r, xs)
 
-	c := w.RefractedColor(comps, 5)
+	c := NewWhitted().RefractedColor(w, comps, 5)
 
 	if !c.Eq(NewColor(0, 0, 0)) {
 		t.Errorf("Got %v, want %v", c, NewColor(0, 0, 0))
@@ -251,7 +251,7 @@ func TestFindRefractedColorAtMaxRecursiveDepth(t *testing.T) {
 	w := NewDefaultWorld()
 
 	s := w.Objects[0]
-	(*s).GetMaterial().SetTransparency(1.0).SetRefractiveIndex(1.5)
+	(*s).GetNewMaterial().(*Material).SetTransparency(1.0).SetRefractiveIndex(1.5)
 
 	r := NewRay(NewPoint(0, 0, -5), NewVec(0, 0, 1))
 
@@ -262,7 +262,7 @@ func TestFindRefractedColorAtMaxRecursiveDepth(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[0], r, xs)
 
-	c := w.RefractedColor(comps, 0)
+	c := NewWhitted().RefractedColor(w, comps, 0)
 
 	if !c.Eq(NewColor(0, 0, 0)) {
 		t.Errorf("Got %v, want %v", c, NewColor(0, 0, 0))
@@ -273,7 +273,7 @@ func TestFindRefractedColorUnderTotalInternalReflection(t *testing.T) {
 	w := NewDefaultWorld()
 	s := w.Objects[0]
 
-	(*s).GetMaterial().SetTransparency(1).SetRefractiveIndex(1.5)
+	(*s).GetNewMaterial().(*Material).SetTransparency(1).SetRefractiveIndex(1.5)
 
 	r := NewRay(NewPoint(0, 0, math.Sqrt(2)/2), NewVec(0, 1, 0))
 
@@ -284,7 +284,7 @@ func TestFindRefractedColorUnderTotalInternalReflection(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[1], r, xs)
 
-	c := w.RefractedColor(comps, 5)
+	c := NewWhitted().RefractedColor(w, comps, 5)
 
 	if !c.Eq(NewColor(0, 0, 0)) {
 		t.Errorf("Got %v, want %v", c, NewColor(0, 0, 0))
@@ -294,10 +294,10 @@ func TestFindRefractedColorUnderTotalInternalReflection(t *testing.T) {
 func TestFindRefractedColor(t *testing.T) {
 	w := NewDefaultWorld()
 	a := w.Objects[0]
-	(*a).GetMaterial().SetPattern(NewTestPattern())
+	(*a).GetNewMaterial().(*Material).SetPattern(NewTestPattern())
 
 	b := w.Objects[1]
-	(*b).GetMaterial().SetTransparency(1).SetRefractiveIndex(1.5)
+	(*b).GetNewMaterial().(*Material).SetTransparency(1).SetRefractiveIndex(1.5)
 
 	r := NewRay(NewPoint(0, 0, 0.1), NewVec(0, 1, 0))
 
@@ -310,7 +310,7 @@ func TestFindRefractedColor(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[2], r, xs)
 
-	c := w.RefractedColor(comps, 5)
+	c := NewWhitted().RefractedColor(w, comps, 5)
 
 	if !c.Eq(NewColor(0, 0.09988796, 0.00472177)) {
 		t.Errorf("Got %v, want %v", c, NewColor(0, 0.09988796, 0.00472177))
@@ -320,13 +320,11 @@ func TestFindRefractedColor(t *testing.T) {
 func TestShadeHitWithATransparentMaterial(t *testing.T) {
 	w := NewDefaultWorld()
 
-	floor := NewPlane().SetTransform(NewTranslation(0, -1, 0))
-	floor.GetMaterial().SetTransparency(0.5).SetRefractiveIndex(1.5)
+	floor := NewPlane().SetNewMaterial(NewMaterial().SetTransparency(0.5).SetRefractiveIndex(1.5)).SetTransform(NewTranslation(0, -1, 0))
 
 	w.AddObject(floor)
 
-	ball := NewSphere().SetTransform(NewTranslation(0, -3.5, -0.5))
-	ball.GetMaterial().SetColor(NewColor(1, 0, 0)).SetAmbient(0.5)
+	ball := NewSphere().SetNewMaterial(NewMaterial().SetColor(NewColor(1, 0, 0)).SetAmbient(0.5)).SetTransform(NewTranslation(0, -3.5, -0.5))
 
 	w.AddObject(ball)
 
@@ -335,7 +333,7 @@ func TestShadeHitWithATransparentMaterial(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[0], r, xs)
 
-	color := w.ShadeHit(comps, 5)
+	color := NewWhitted().ShadeHit(w, comps, 5)
 
 	if !color.Eq(NewColor(0.93642, 0.68642, 0.68642)) {
 		t.Errorf("Got %v, want %v", color, NewColor(0.93642, 0.68642, 0.68642))
@@ -347,13 +345,11 @@ func TestShadeHitWithAReflectiveAndTransparentMaterial(t *testing.T) {
 
 	r := NewRay(NewPoint(0, 0, -3), NewVec(0, -math.Sqrt(2)/2, math.Sqrt(2)/2))
 
-	floor := NewPlane().SetTransform(NewTranslation(0, -1, 0))
-	floor.GetMaterial().SetReflective(0.5).SetTransparency(0.5).SetRefractiveIndex(1.5)
+	floor := NewPlane().SetNewMaterial(NewMaterial().SetReflective(0.5).SetTransparency(0.5).SetRefractiveIndex(1.5)).SetTransform(NewTranslation(0, -1, 0))
 
 	w.AddObject(floor)
 
-	ball := NewSphere().SetTransform(NewTranslation(0, -3.5, -0.5))
-	ball.GetMaterial().SetColor(NewColor(1, 0, 0)).SetAmbient(0.5)
+	ball := NewSphere().SetNewMaterial(NewMaterial().SetColor(NewColor(1, 0, 0)).SetAmbient(0.5)).SetTransform(NewTranslation(0, -3.5, -0.5))
 
 	w.AddObject(ball)
 
@@ -363,7 +359,7 @@ func TestShadeHitWithAReflectiveAndTransparentMaterial(t *testing.T) {
 
 	comps := PrepareComputationsWithHit(xs[0], r, xs)
 
-	color := w.ShadeHit(comps, 5)
+	color := NewWhitted().ShadeHit(w, comps, 5)
 
 	if !color.Eq(NewColor(0.93391, 0.69643, 0.69243)) {
 		t.Errorf("Got %v, want %v", color, NewColor(0.93391, 0.69643, 0.69243))